@@ -0,0 +1,746 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skssmd/norm/core/registry"
+)
+
+// ErrAggregateNotMergeable is returned when an aggregate query spans several
+// shards but its per-shard results cannot be combined in the application
+var ErrAggregateNotMergeable = errors.New("aggregate cannot be merged across shards")
+
+var (
+	aggCallRe     = regexp.MustCompile(`(?i)\b(count|sum|min|max|avg)\s*\(`)
+	aggFuncRe     = regexp.MustCompile(`(?is)^(count|sum|min|max|avg)\s*\(\s*(distinct\s+)?(.*)\)$`)
+	aliasRe       = regexp.MustCompile(`(?is)^(.*?)\s+as\s+(?:"(\w+)"|(\w+))\s*$`)
+	identRe       = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+	funcNameRe    = regexp.MustCompile(`^([A-Za-z_]\w*)\s*\(`)
+	havingOrRe    = regexp.MustCompile(`(?i)\bor\b`)
+	havingAndRe   = regexp.MustCompile(`(?i)\s+and\s+`)
+	havingCondRe  = regexp.MustCompile(`^(.+?)\s*(>=|<=|<>|!=|=|>|<)\s*(.+)$`)
+	placeholderRe = regexp.MustCompile(`^\$(\d+)$`)
+)
+
+// --- Terminal aggregates ---
+
+// Sum returns SUM(column) over the rows matched by the query (0 when no rows match)
+func (q *Query[T]) Sum(ctx context.Context, column string) (float64, error) {
+	v, err := q.aggregateValue(ctx, "SUM("+column+")")
+	f, _ := toFloat64(v)
+	return f, err
+}
+
+// Avg returns AVG(column) over the rows matched by the query (0 when no rows match)
+// Across shards it is computed as the total sum divided by the total count.
+func (q *Query[T]) Avg(ctx context.Context, column string) (float64, error) {
+	v, err := q.aggregateValue(ctx, "AVG("+column+")")
+	f, _ := toFloat64(v)
+	return f, err
+}
+
+// Min scans MIN(column) over the rows matched by the query into dest
+// Usage: var first time.Time; Table("orders").Min(ctx, "created_at", &first)
+func (q *Query[T]) Min(ctx context.Context, column string, dest interface{}) error {
+	return q.aggregateInto(ctx, "MIN("+column+")", dest)
+}
+
+// Max scans MAX(column) over the rows matched by the query into dest
+func (q *Query[T]) Max(ctx context.Context, column string, dest interface{}) error {
+	return q.aggregateInto(ctx, "MAX("+column+")", dest)
+}
+
+// CountDistinct returns COUNT(DISTINCT column) over the rows matched by the query
+// Across shards the distinct values are collected and de-duplicated in the app,
+// except for the shard key whose values never repeat across shards.
+func (q *Query[T]) CountDistinct(ctx context.Context, column string) (int64, error) {
+	sub := q.aggregateQuery([]string{"COUNT(DISTINCT " + column + ")"})
+	shards := sub.scatterShards()
+	if len(shards) == 0 {
+		v, err := sub.aggregateValue(ctx, "COUNT(DISTINCT "+column+")")
+		n, _ := toInt64(v)
+		return n, err
+	}
+
+	tm, _ := registry.GetModel(q.table)
+	if tm != nil && tm.IsKeySharded() && bareColumn(column) == tm.ShardKey {
+		sql, args, err := sub.builder.Build()
+		if err != nil {
+			return 0, err
+		}
+		return sub.scatterCount(ctx, shards, sql, args)
+	}
+
+	sub.builder.columns = []string{"DISTINCT " + column + " AS value"}
+	sql, args, err := sub.builder.Build()
+	if err != nil {
+		return 0, err
+	}
+	parts, scatterErr := sub.fanOut(ctx, shards, func(ctx context.Context, shard string) (interface{}, error) {
+		return sub.queryShardMaps(ctx, shard, sql, args)
+	})
+
+	seen := make(map[string]struct{})
+	for _, p := range parts {
+		for _, row := range p.value.([]map[string]interface{}) {
+			if v := row["value"]; v != nil {
+				seen[fmt.Sprintf("%T=%v", v, v)] = struct{}{}
+			}
+		}
+	}
+	return int64(len(seen)), scatterErr
+}
+
+// aggregateQuery copies q as a SELECT of columns over the same rows
+// Grouping, ordering and windowing are dropped so the result is a single row.
+func (q *Query[T]) aggregateQuery(columns []string) *Query[T] {
+	b := *q.builder
+	b.queryType = "select"
+	b.columns = columns
+	b.groupBy = nil
+	b.having = ""
+	b.havingArgs = nil
+	b.orderBy = ""
+	b.limit = 0
+	b.offset = 0
+
+	sub := *q
+	sub.builder = &b
+	return &sub
+}
+
+// aggregateValue runs a single aggregate expression and returns its value
+func (q *Query[T]) aggregateValue(ctx context.Context, expr string) (interface{}, error) {
+	rows, err := q.aggregateQuery([]string{expr + " AS value"}).aggregateMaps(ctx)
+	if len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0]["value"], err
+}
+
+// aggregateInto runs a single aggregate expression and stores its value in dest
+func (q *Query[T]) aggregateInto(ctx context.Context, expr string, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer")
+	}
+
+	v, err := q.aggregateValue(ctx, expr)
+	if v == nil {
+		if err != nil {
+			return err
+		}
+		return ErrNoRows
+	}
+
+	target := destValue.Elem()
+	if target.Kind() == reflect.Interface {
+		target.Set(reflect.ValueOf(v))
+	} else if serr := setField(target, v); serr != nil {
+		return q.scanOptions().errorAt(expr, valueTypeName(v), target.Type(), nil, serr)
+	}
+	return err
+}
+
+// aggregateMaps runs an aggregate SELECT and returns its rows as maps
+// Tables spread over several shards get partial aggregates per shard merged in the app.
+func (q *Query[T]) aggregateMaps(ctx context.Context) ([]map[string]interface{}, error) {
+	if shards := q.scatterShards(); len(shards) > 0 {
+		return q.scatterAggregate(ctx, shards)
+	}
+
+	rows, err := q.queryMaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		normalizeNumerics(row)
+	}
+	return rows, nil
+}
+
+// isAggregate reports whether the SELECT groups rows or selects aggregate functions
+func (qb *QueryBuilder) isAggregate() bool {
+	if len(qb.groupBy) > 0 {
+		return true
+	}
+	for _, col := range qb.columns {
+		if aggCallRe.MatchString(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Scatter-gather aggregation ---
+
+// executeScatterAggregate runs a grouped/aggregate SELECT on every shard and
+// returns the merged groups
+func (q *Query[T]) executeScatterAggregate(ctx context.Context, dest interface{}, singleRow bool, shards []string) error {
+	if singleRow {
+		q.builder.Limit(1)
+	}
+
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return err
+	}
+
+	// Check cache
+	cacheQuery := "SCATTER-AGG:" + sql
+	if cachedData, hit, _ := q.checkCache(ctx, cacheQuery, args); hit {
+		if dest != nil {
+			return json.Unmarshal(cachedData, dest)
+		}
+		var results []map[string]interface{}
+		if err := json.Unmarshal(cachedData, &results); err != nil {
+			return fmt.Errorf("failed to unmarshal cached data: %w", err)
+		}
+		if IsDebugMode() {
+			q.printResults(results, true)
+		}
+		return nil
+	}
+
+	merged, scatterErr := q.scatterAggregate(ctx, shards)
+	if scatterErr != nil && !isPartial(scatterErr) {
+		return scatterErr
+	}
+
+	if dest == nil {
+		if scatterErr == nil {
+			_ = q.setCache(ctx, cacheQuery, args, merged)
+		}
+		q.printResults(merged, false)
+		return scatterErr
+	}
+
+	if err := scanMapsToDest(merged, dest, q.scanOptions()); err != nil {
+		return err
+	}
+	if scatterErr == nil {
+		_ = q.setCache(ctx, cacheQuery, args, dest)
+	}
+	return scatterErr
+}
+
+// scatterAggregate pushes partial aggregates to every shard, merges the groups,
+// then applies HAVING, ORDER BY, LIMIT and OFFSET globally
+func (q *Query[T]) scatterAggregate(ctx context.Context, shards []string) ([]map[string]interface{}, error) {
+	plan, err := planAggregate(q.builder)
+	if err != nil {
+		return nil, fmt.Errorf("table '%s': %w", q.table, err)
+	}
+
+	shardBuilder := *q.builder
+	shardBuilder.columns = plan.shardColumns()
+	shardBuilder.having = ""
+	shardBuilder.havingArgs = nil
+	shardBuilder.orderBy = ""
+	shardBuilder.limit = 0
+	shardBuilder.offset = 0
+
+	sql, args, err := shardBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+	debugLog("Scatter aggregate table=%s shard sql=%s", q.table, sql)
+
+	parts, scatterErr := q.fanOut(ctx, shards, func(ctx context.Context, shard string) (interface{}, error) {
+		return q.queryShardMaps(ctx, shard, sql, args)
+	})
+	if parts == nil {
+		return nil, scatterErr
+	}
+
+	merged := plan.filter(plan.merge(parts))
+	if err := sortMaps(merged, plan.order); err != nil {
+		return nil, fmt.Errorf("table '%s': %w", q.table, err)
+	}
+	plan.stripHidden(merged)
+	return windowMaps(merged, q.builder.limit, q.builder.offset), scatterErr
+}
+
+// isPartial reports whether err is a fan-out error that still carries results
+func isPartial(err error) bool {
+	var se *ScatterError
+	return errors.As(err, &se) && se.Partial
+}
+
+// aggColumn is one output column of an aggregate SELECT
+type aggColumn struct {
+	expr   string // column as written, without alias
+	fn     string // COUNT, SUM, MIN, MAX or AVG; empty for grouping columns
+	arg    string // aggregate argument
+	key    string // name of the column in the result rows
+	hidden bool   // fetched only to evaluate HAVING / ORDER BY
+}
+
+// havingCond is one "<aggregate> <op> <value>" term of a HAVING clause
+type havingCond struct {
+	key   string
+	op    string
+	value interface{}
+}
+
+// aggPlan describes how partial per-shard aggregates are combined
+type aggPlan struct {
+	columns []aggColumn
+	having  []havingCond
+	order   []orderTerm
+}
+
+// planAggregate parses the SELECT list, HAVING and ORDER BY of an aggregate query
+func planAggregate(qb *QueryBuilder) (*aggPlan, error) {
+	plan := &aggPlan{}
+	for _, col := range qb.columns {
+		c, err := parseAggColumn(col)
+		if err != nil {
+			return nil, err
+		}
+		plan.columns = append(plan.columns, c)
+	}
+
+	if err := plan.parseHaving(qb.having, qb.havingArgs); err != nil {
+		return nil, err
+	}
+
+	for _, part := range strings.Split(qb.orderBy, ",") {
+		expr := strings.TrimSpace(part)
+		if expr == "" {
+			continue
+		}
+		expr, desc, nullsFirst := splitOrderTerm(expr)
+		key, err := plan.resolve(expr)
+		if err != nil {
+			key = bareColumn(expr)
+		}
+		plan.order = append(plan.order, orderTerm{column: key, desc: desc, nullsFirst: nullsFirst})
+	}
+	return plan, nil
+}
+
+// parseAggColumn classifies a SELECT column as an aggregate or a grouping column
+func parseAggColumn(col string) (aggColumn, error) {
+	expr, alias := strings.TrimSpace(col), ""
+	if m := aliasRe.FindStringSubmatch(expr); m != nil {
+		expr, alias = strings.TrimSpace(m[1]), m[2]
+		if alias == "" {
+			alias = strings.ToLower(m[3]) // unquoted identifiers are folded to lower case
+		}
+	}
+	if expr == "*" || strings.HasSuffix(expr, ".*") {
+		return aggColumn{}, fmt.Errorf("%w: %s in a grouped SELECT (list the grouped columns)", ErrAggregateNotMergeable, expr)
+	}
+
+	if m := aggFuncRe.FindStringSubmatch(expr); m != nil && balancedParens(m[3]) {
+		fn := strings.ToUpper(m[1])
+		if m[2] != "" && fn != "MIN" && fn != "MAX" {
+			return aggColumn{}, fmt.Errorf("%w: %s(DISTINCT ...) (use CountDistinct)", ErrAggregateNotMergeable, fn)
+		}
+		key := alias
+		if key == "" {
+			key = strings.ToLower(fn) // Postgres names unaliased aggregates after the function
+		}
+		return aggColumn{expr: expr, fn: fn, arg: strings.TrimSpace(m[3]), key: key}, nil
+	}
+
+	if aggCallRe.MatchString(expr) {
+		return aggColumn{}, fmt.Errorf("%w: expression %q (select the aggregates separately)", ErrAggregateNotMergeable, expr)
+	}
+
+	key := alias
+	if key == "" {
+		if m := funcNameRe.FindStringSubmatch(expr); m != nil {
+			key = strings.ToLower(m[1])
+		} else {
+			key = bareColumn(expr)
+		}
+	}
+	return aggColumn{expr: expr, key: key}, nil
+}
+
+// parseHaving parses an AND-list of comparisons against aggregates or selected columns
+func (p *aggPlan) parseHaving(having string, args []interface{}) error {
+	if strings.TrimSpace(having) == "" {
+		return nil
+	}
+	if havingOrRe.MatchString(having) {
+		return fmt.Errorf("%w: HAVING with OR", ErrAggregateNotMergeable)
+	}
+
+	for _, part := range havingAndRe.Split(having, -1) {
+		m := havingCondRe.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return fmt.Errorf("%w: HAVING condition %q", ErrAggregateNotMergeable, part)
+		}
+		key, err := p.resolve(m[1])
+		if err != nil {
+			return err
+		}
+		value, err := havingOperand(strings.TrimSpace(m[3]), args)
+		if err != nil {
+			return err
+		}
+		p.having = append(p.having, havingCond{key: key, op: m[2], value: value})
+	}
+	return nil
+}
+
+// havingOperand resolves the right-hand side of a HAVING comparison
+func havingOperand(s string, args []interface{}) (interface{}, error) {
+	if m := placeholderRe.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(args) {
+			return nil, fmt.Errorf("HAVING placeholder $%d has no argument", n)
+		}
+		return args[n-1], nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return nil, fmt.Errorf("%w: HAVING operand %q", ErrAggregateNotMergeable, s)
+}
+
+// resolve maps an expression to its result key, adding a hidden aggregate column if needed
+func (p *aggPlan) resolve(expr string) (string, error) {
+	n := normExpr(expr)
+	for _, c := range p.columns {
+		if n == strings.ToLower(c.key) || n == normExpr(c.expr) {
+			return c.key, nil
+		}
+	}
+	if identRe.MatchString(expr) {
+		for _, c := range p.columns {
+			if c.fn == "" && bareColumn(c.expr) == bareColumn(expr) {
+				return c.key, nil
+			}
+		}
+	}
+
+	c, err := parseAggColumn(expr)
+	if err != nil {
+		return "", err
+	}
+	if c.fn == "" {
+		return "", fmt.Errorf("%w: %q is neither selected nor an aggregate", ErrAggregateNotMergeable, expr)
+	}
+	c.key = fmt.Sprintf("__norm_agg_%d", len(p.columns))
+	c.hidden = true
+	p.columns = append(p.columns, c)
+	return c.key, nil
+}
+
+// shardColumns returns the SELECT list sent to each shard
+// AVG is split into SUM and COUNT so it can be recombined exactly.
+func (p *aggPlan) shardColumns() []string {
+	cols := make([]string, 0, len(p.columns))
+	for _, c := range p.columns {
+		switch c.fn {
+		case "":
+			cols = append(cols, fmt.Sprintf(`%s AS "%s"`, c.expr, c.key))
+		case "AVG":
+			cols = append(cols,
+				fmt.Sprintf(`SUM(%s) AS "%s"`, c.arg, avgSumKey(c.key)),
+				fmt.Sprintf(`COUNT(%s) AS "%s"`, c.arg, avgCountKey(c.key)))
+		default:
+			cols = append(cols, fmt.Sprintf(`%s(%s) AS "%s"`, c.fn, c.arg, c.key))
+		}
+	}
+	return cols
+}
+
+func avgSumKey(key string) string   { return "__norm_sum_" + key }
+func avgCountKey(key string) string { return "__norm_count_" + key }
+
+// merge combines the per-shard rows of each group
+// COUNT, SUM and the parts of AVG are added exactly and converted once at the
+// end, so the merged values have the types a single pool returns.
+func (p *aggPlan) merge(parts []shardResult) []map[string]interface{} {
+	groups := make(map[string]map[string]interface{})
+	merged := []map[string]interface{}{}
+
+	for _, part := range parts {
+		for _, row := range part.value.([]map[string]interface{}) {
+			for _, c := range p.columns {
+				switch c.fn {
+				case "COUNT", "SUM", "AVG":
+				default:
+					row[c.key] = normalizeNumeric(row[c.key])
+				}
+			}
+
+			var gk strings.Builder
+			for _, c := range p.columns {
+				if c.fn == "" {
+					fmt.Fprintf(&gk, "%T=%v\x00", row[c.key], row[c.key])
+				}
+			}
+
+			acc, ok := groups[gk.String()]
+			if !ok {
+				groups[gk.String()] = row
+				merged = append(merged, row)
+				continue
+			}
+
+			for _, c := range p.columns {
+				switch c.fn {
+				case "COUNT", "SUM":
+					acc[c.key] = addValues(acc[c.key], row[c.key])
+				case "AVG":
+					acc[avgSumKey(c.key)] = addValues(acc[avgSumKey(c.key)], row[avgSumKey(c.key)])
+					acc[avgCountKey(c.key)] = addValues(acc[avgCountKey(c.key)], row[avgCountKey(c.key)])
+				case "MIN":
+					if compareValues(reflect.ValueOf(row[c.key]), reflect.ValueOf(acc[c.key])) < 0 {
+						acc[c.key] = row[c.key]
+					}
+				case "MAX":
+					if row[c.key] != nil && (acc[c.key] == nil || compareValues(reflect.ValueOf(row[c.key]), reflect.ValueOf(acc[c.key])) > 0) {
+						acc[c.key] = row[c.key]
+					}
+				}
+			}
+		}
+	}
+
+	// AVG = total sum / total count
+	for _, row := range merged {
+		for _, c := range p.columns {
+			switch c.fn {
+			case "COUNT", "SUM":
+				row[c.key] = normalizeNumeric(row[c.key])
+			case "AVG":
+				row[c.key] = divideValues(row[avgSumKey(c.key)], row[avgCountKey(c.key)])
+				delete(row, avgSumKey(c.key))
+				delete(row, avgCountKey(c.key))
+			}
+		}
+	}
+	return merged
+}
+
+// filter applies HAVING to the merged groups
+// Comparisons against NULL are false, as in SQL.
+func (p *aggPlan) filter(rows []map[string]interface{}) []map[string]interface{} {
+	if len(p.having) == 0 {
+		return rows
+	}
+	kept := rows[:0]
+	for _, row := range rows {
+		ok := true
+		for _, h := range p.having {
+			if row[h.key] == nil || h.value == nil {
+				ok = false
+				break
+			}
+			c := compareValues(reflect.ValueOf(row[h.key]), reflect.ValueOf(h.value))
+			switch h.op {
+			case "=":
+				ok = c == 0
+			case "<>", "!=":
+				ok = c != 0
+			case ">":
+				ok = c > 0
+			case ">=":
+				ok = c >= 0
+			case "<":
+				ok = c < 0
+			case "<=":
+				ok = c <= 0
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// stripHidden removes columns that were only fetched for HAVING / ORDER BY
+func (p *aggPlan) stripHidden(rows []map[string]interface{}) {
+	for _, c := range p.columns {
+		if !c.hidden {
+			continue
+		}
+		for _, row := range rows {
+			delete(row, c.key)
+		}
+	}
+}
+
+// --- Value helpers ---
+
+// normalizeNumerics converts NUMERIC results (e.g. SUM of bigint) to int64 or float64
+func normalizeNumerics(row map[string]interface{}) {
+	for k, v := range row {
+		row[k] = normalizeNumeric(v)
+	}
+}
+
+// normalizeNumeric converts a NUMERIC (or an exact merged total) to int64 when
+// it is a whole number that fits, and to float64 otherwise
+func normalizeNumeric(v interface{}) interface{} {
+	switch n := v.(type) {
+	case pgtype.Numeric:
+		if !n.Valid {
+			return nil
+		}
+		if i, err := n.Int64Value(); err == nil {
+			return i.Int64
+		}
+		if f, err := n.Float64Value(); err == nil {
+			return f.Float64
+		}
+	case *big.Rat:
+		if n.IsInt() && n.Num().IsInt64() {
+			return n.Num().Int64()
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
+
+// addValues adds two partial aggregates (NULL + x = x)
+// Integers and NUMERICs are added exactly, spilling into a *big.Rat when they
+// don't fit an int64; float partials make the total a float64.
+func addValues(a, b interface{}) interface{} {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	ai, aok := toInt64(a)
+	bi, bok := toInt64(b)
+	if aok && bok {
+		if sum := ai + bi; (sum > ai) == (bi > 0) {
+			return sum
+		}
+	}
+	ar, aok := toRat(a)
+	br, bok := toRat(b)
+	if aok && bok {
+		return new(big.Rat).Add(ar, br)
+	}
+	af, _ := toFloat64(a)
+	bf, _ := toFloat64(b)
+	return af + bf
+}
+
+// divideValues returns sum / count for a merged AVG, or nil when count is zero
+func divideValues(sum, count interface{}) interface{} {
+	n, _ := toInt64(count)
+	if sum == nil || n <= 0 {
+		return nil
+	}
+	if r, ok := toRat(sum); ok {
+		return normalizeNumeric(r.Quo(r, big.NewRat(n, 1)))
+	}
+	f, _ := toFloat64(sum)
+	return f / float64(n)
+}
+
+// toRat converts an integer, a finite NUMERIC or a *big.Rat to a new *big.Rat
+func toRat(v interface{}) (*big.Rat, bool) {
+	if i, ok := toInt64(v); ok {
+		return new(big.Rat).SetInt64(i), true
+	}
+	switch n := v.(type) {
+	case *big.Rat:
+		return new(big.Rat).Set(n), true
+	case pgtype.Numeric:
+		if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite || n.Int == nil {
+			return nil, false
+		}
+		e := int64(n.Exp)
+		if e < 0 {
+			e = -e
+		}
+		r := new(big.Rat).SetInt(n.Int)
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(e), nil))
+		if n.Exp >= 0 {
+			return r.Mul(r, scale), true
+		}
+		return r.Quo(r, scale), true
+	}
+	return nil, false
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64 {
+		return rv.Float(), true
+	}
+	switch n := v.(type) {
+	case pgtype.Numeric:
+		if f, err := n.Float64Value(); err == nil && f.Valid {
+			return f.Float64, true
+		}
+	case *big.Rat:
+		f, _ := n.Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// normExpr normalizes an SQL expression for comparison (case and whitespace)
+func normExpr(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// bareColumn strips the table prefix from a column ("users.id" => "id")
+func bareColumn(col string) string {
+	col = strings.TrimSpace(col)
+	if i := strings.LastIndex(col, "."); i >= 0 {
+		return col[i+1:]
+	}
+	return col
+}
+
+// balancedParens reports whether every parenthesis in s is closed in order
+func balancedParens(s string) bool {
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func numeric(i int64, exp int32) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(i), Exp: exp, Valid: true}
+}
+
+func TestAggPlanMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		parts   [][]map[string]interface{}
+		want    []map[string]interface{}
+	}{
+		{
+			name:    "count and sum per group",
+			columns: []string{"status", "COUNT(*) AS n", "SUM(amount) AS total"},
+			parts: [][]map[string]interface{}{
+				{{"status": "paid", "n": int64(2), "total": int64(10)}, {"status": "open", "n": int64(1), "total": int64(5)}},
+				{{"status": "paid", "n": int64(3), "total": int64(7)}},
+			},
+			want: []map[string]interface{}{
+				{"status": "paid", "n": int64(5), "total": int64(17)},
+				{"status": "open", "n": int64(1), "total": int64(5)},
+			},
+		},
+		{
+			name:    "numeric sum is exact",
+			columns: []string{"SUM(price)"},
+			parts: [][]map[string]interface{}{
+				{{"sum": numeric(1, -1)}},
+				{{"sum": numeric(2, -1)}},
+			},
+			want: []map[string]interface{}{{"sum": 0.3}},
+		},
+		{
+			name:    "whole numeric sum is an integer",
+			columns: []string{"SUM(price)"},
+			parts: [][]map[string]interface{}{
+				{{"sum": numeric(150, -2)}},
+				{{"sum": numeric(350, -2)}},
+			},
+			want: []map[string]interface{}{{"sum": int64(5)}},
+		},
+		{
+			name:    "integer overflow spills into numeric",
+			columns: []string{"SUM(n)"},
+			parts: [][]map[string]interface{}{
+				{{"sum": int64(math.MaxInt64)}},
+				{{"sum": int64(math.MaxInt64)}},
+			},
+			want: []map[string]interface{}{{"sum": 2 * float64(math.MaxInt64)}},
+		},
+		{
+			name:    "float sum",
+			columns: []string{"SUM(score)"},
+			parts: [][]map[string]interface{}{
+				{{"sum": 1.5}},
+				{{"sum": 2.25}},
+			},
+			want: []map[string]interface{}{{"sum": 3.75}},
+		},
+		{
+			name:    "null sums are skipped",
+			columns: []string{"SUM(n)"},
+			parts: [][]map[string]interface{}{
+				{{"sum": nil}},
+				{{"sum": int64(4)}},
+			},
+			want: []map[string]interface{}{{"sum": int64(4)}},
+		},
+		{
+			name:    "avg from sum and count",
+			columns: []string{"AVG(n)"},
+			parts: [][]map[string]interface{}{
+				{{"__norm_sum_avg": int64(3), "__norm_count_avg": int64(2)}},
+				{{"__norm_sum_avg": int64(4), "__norm_count_avg": int64(2)}},
+			},
+			want: []map[string]interface{}{{"avg": 1.75}},
+		},
+		{
+			name:    "avg of no rows",
+			columns: []string{"AVG(n)"},
+			parts: [][]map[string]interface{}{
+				{{"__norm_sum_avg": nil, "__norm_count_avg": int64(0)}},
+			},
+			want: []map[string]interface{}{{"avg": nil}},
+		},
+		{
+			name:    "min and max",
+			columns: []string{"MIN(n)", "MAX(n)"},
+			parts: [][]map[string]interface{}{
+				{{"min": int64(3), "max": int64(9)}},
+				{{"min": int64(1), "max": nil}},
+				{{"min": nil, "max": int64(12)}},
+			},
+			want: []map[string]interface{}{{"min": int64(1), "max": int64(12)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planAggregate(&QueryBuilder{columns: tt.columns})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parts []shardResult
+			for _, rows := range tt.parts {
+				parts = append(parts, shardResult{value: rows})
+			}
+			if got := plan.merge(parts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHaving(t *testing.T) {
+	tests := []struct {
+		name    string
+		having  string
+		args    []interface{}
+		want    []havingCond
+		hidden  int // hidden aggregate columns added
+		wantErr bool
+	}{
+		{"empty", "", nil, nil, 0, false},
+		{"selected alias", "n > 2", nil, []havingCond{{"n", ">", 2.0}}, 0, false},
+		{"selected aggregate", "COUNT(*) >= $1", []interface{}{5}, []havingCond{{"n", ">=", 5}}, 0, false},
+		{"hidden aggregate", "SUM(amount) < 100", nil, []havingCond{{"__norm_agg_2", "<", 100.0}}, 1, false},
+		{"and", "n > 1 AND status = 'it''s'", nil, []havingCond{{"n", ">", 1.0}, {"status", "=", "it's"}}, 0, false},
+		{"or", "n > 1 OR n < 0", nil, nil, 0, true},
+		{"unknown column", "region = 'eu'", nil, nil, 0, true},
+		{"not a comparison", "n IS NULL", nil, nil, 0, true},
+		{"missing argument", "n > $2", []interface{}{1}, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planAggregate(&QueryBuilder{columns: []string{"status", "COUNT(*) AS n"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = plan.parseHaving(tt.having, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHaving() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(plan.having, tt.want) {
+				t.Errorf("having = %v, want %v", plan.having, tt.want)
+			}
+			if got := len(plan.columns) - 2; got != tt.hidden {
+				t.Errorf("%d hidden columns, want %d", got, tt.hidden)
+			}
+		})
+	}
+}
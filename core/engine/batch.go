@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Batchable is a query that can be queued on a Batch (any *Query)
+type Batchable interface {
+	prepareBatch(ctx context.Context) (querier, string, []interface{}, error)
+	scanOptions() scanOptions
+}
+
+// Batch queues queries and sends them with one round trip per pool
+type Batch struct {
+	items []batchItem
+}
+
+// batchItem is one queued query and where its rows go
+type batchItem struct {
+	query Batchable
+	dest  interface{}
+}
+
+// BatchResult is the outcome of one query of a Batch
+type BatchResult struct {
+	Rows         []map[string]interface{} // returned rows (nil when a destination was given to Add)
+	RowsAffected int64
+	Err          error
+
+	opts scanOptions
+}
+
+// Scan fills dest (pointer to a struct or slice of structs) from the result rows
+func (r *BatchResult) Scan(dest interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return scanMapsToDest(r.Rows, dest, r.opts)
+}
+
+// NewBatch creates an empty batch
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Add queues a query; rows are scanned into dest when given, otherwise kept in the result
+// Usage:
+//
+//	norm.Batch().
+//	    Add(norm.Table("users").Select().Where("id = $1", id), &user).
+//	    Add(norm.Table("orders").Select().Where("user_id = $1", id), &orders).
+//	    Add(norm.Table("users").Update("last_seen", time.Now()).Where("id = $1", id)).
+//	    Send(ctx)
+func (b *Batch) Add(q Batchable, dest ...interface{}) *Batch {
+	item := batchItem{query: q}
+	if len(dest) > 0 {
+		item.dest = dest[0]
+	}
+	b.items = append(b.items, item)
+	return b
+}
+
+// Len returns the number of queued queries
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Send routes every query, sends one pgx.Batch per pool (pools in parallel) and
+// returns one result per query in the order they were added
+// The error joins the errors of all failed queries (nil when all succeeded).
+// Queries sent to the same pool run in one implicit transaction: a failure
+// rolls back that pool's earlier writes and fails the queries after it.
+func (b *Batch) Send(ctx context.Context) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(b.items))
+
+	// Group by the connection each query routes to
+	type group struct {
+		db      querier
+		batch   *pgx.Batch
+		indexes []int
+	}
+	var groups []*group
+	byConn := make(map[querier]*group)
+	for i, item := range b.items {
+		results[i] = &BatchResult{opts: item.query.scanOptions()}
+		db, sql, args, err := item.query.prepareBatch(ctx)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		g, ok := byConn[db]
+		if !ok {
+			g = &group{db: db, batch: &pgx.Batch{}}
+			byConn[db] = g
+			groups = append(groups, g)
+		}
+		g.batch.Queue(sql, args...)
+		g.indexes = append(g.indexes, i)
+	}
+	debugLog("Batch of %d queries over %d pool(s)", len(b.items), len(groups))
+
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			br := g.db.SendBatch(ctx, g.batch)
+			defer br.Close()
+			for _, i := range g.indexes {
+				receiveBatch(br, b.items[i], results[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("batch query %d: %w", i, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// receiveBatch reads the next result of br into res (and the item's destination)
+func receiveBatch(br pgx.BatchResults, item batchItem, res *BatchResult) {
+	rows, err := br.Query()
+	if err != nil {
+		res.Err = fmt.Errorf("query execution failed: %w", err)
+		return
+	}
+
+	if item.dest != nil {
+		err = scanRowsToDest(rows, item.dest, res.opts)
+	} else {
+		res.Rows, err = scanRowsToMap(rows)
+	}
+	rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		res.Err = err
+		return
+	}
+	res.RowsAffected = rows.CommandTag().RowsAffected()
+}
+
+// prepareBatch routes and builds the query for a Batch
+// Queries that need more than one statement or pool (joins, fan-outs,
+// soft-key cascades) can't be batched and report an error instead.
+func (q *Query[T]) prepareBatch(ctx context.Context) (querier, string, []interface{}, error) {
+	if q.err != nil {
+		return nil, "", nil, q.err
+	}
+
+	if q.rawSQL != "" {
+		pool, err := q.rawPool()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		db, err := q.conn(ctx, pool)
+		return db, q.rawSQL, q.rawArgs, err
+	}
+
+	if q.builder == nil {
+		return nil, "", nil, errors.New("query has no table")
+	}
+	if q.joinContext != nil {
+		return nil, "", nil, errors.New("joins cannot be batched; use All")
+	}
+	if len(q.scatterShards()) > 0 {
+		return nil, "", nil, fmt.Errorf("query on '%s' spans several shards and cannot be batched", q.table)
+	}
+	if q.hasSoftCascade() {
+		return nil, "", nil, fmt.Errorf("delete from '%s' cascades to soft keys and cannot be batched; use Exec", q.table)
+	}
+	if tm := q.keyShardedTable(); tm != nil {
+		_, pinned, err := q.shardKeyValue(tm)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		switch q.builder.queryType {
+		case "bulkinsert":
+			return nil, "", nil, fmt.Errorf("bulk insert into row-sharded '%s' cannot be batched; use Exec", q.table)
+		case "update", "delete":
+			if !pinned {
+				return nil, "", nil, fmt.Errorf("%w: batched %s on '%s'", ErrShardKeyRequired, q.builder.queryType, q.table)
+			}
+		}
+	}
+	if err := q.checkSoftKeys(ctx); err != nil {
+		return nil, "", nil, err
+	}
+
+	pool, err := q.getPool()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	db, err := q.conn(ctx, pool)
+	return db, sql, args, err
+}
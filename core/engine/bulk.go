@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skssmd/norm/core/driver"
+)
+
+// BulkConfig controls how BulkInsert writes its rows
+type BulkConfig struct {
+	CopyThreshold int // rows at which COPY is used instead of INSERT ... VALUES (0 = 500, <0 = never)
+	ChunkSize     int // max rows per statement (0 = as many as the bind parameter limit allows; 10000 per COPY)
+}
+
+// defaultCopyChunkSize bounds a single COPY when BulkConfig.ChunkSize is not set
+const defaultCopyChunkSize = 10000
+
+var (
+	bulkCfg   BulkConfig
+	bulkCfgMu sync.RWMutex
+)
+
+// SetBulkConfig sets the global bulk insert configuration
+func SetBulkConfig(cfg BulkConfig) {
+	bulkCfgMu.Lock()
+	defer bulkCfgMu.Unlock()
+	bulkCfg = cfg
+}
+
+func getBulkConfig() BulkConfig {
+	bulkCfgMu.RLock()
+	defer bulkCfgMu.RUnlock()
+	cfg := bulkCfg
+	if cfg.CopyThreshold == 0 {
+		cfg.CopyThreshold = 500
+	}
+	return cfg
+}
+
+// execBulk inserts rows of the bulk insert into pool and returns the rows inserted
+// Large batches are streamed with COPY unless the statement needs ON CONFLICT or
+// RETURNING, in chunks of ChunkSize rows; multi-VALUES inserts are split to stay
+// under the bind parameter limit. When more than one statement is needed they run in one transaction
+// (the query's own Tx, if any), so a failed chunk inserts nothing.
+// targets (aligned with rows, may be nil) receive the rows of RETURNING.
+func (q *Query[T]) execBulk(ctx context.Context, pool *driver.PGPool, rows [][]interface{}, targets []reflect.Value) (int64, error) {
+	qb := q.builder
+	if len(qb.bulkColumns) == 0 || len(rows) == 0 {
+		// Let the builder report what's missing
+		sub := *qb
+		sub.bulkRows = rows
+		_, _, err := sub.Build()
+		return 0, err
+	}
+
+	cfg := getBulkConfig()
+	useCopy := cfg.CopyThreshold > 0 && len(rows) >= cfg.CopyThreshold &&
+		!qb.hasConflict() && qb.returningColumns == nil && !rowsHaveExpression(rows)
+
+	size := cfg.ChunkSize
+	if useCopy && size <= 0 {
+		size = defaultCopyChunkSize
+	}
+	if !useCopy {
+		perStatement, err := qb.rowsPerStatement(rows)
+		if err != nil {
+			return 0, err
+		}
+		if size <= 0 || size > perStatement {
+			size = perStatement
+		}
+	}
+	if size <= 0 || size > len(rows) {
+		size = len(rows)
+	}
+	chunks := (len(rows) + size - 1) / size
+
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return 0, err
+	}
+	var tx pgx.Tx
+	if chunks > 1 && q.tx == nil {
+		if tx, err = pool.Pool.Begin(ctx); err != nil {
+			return 0, fmt.Errorf("failed to begin bulk insert transaction: %w", err)
+		}
+		defer tx.Rollback(context.WithoutCancel(ctx))
+		db = tx
+	}
+	debugLog("Bulk insert table=%s rows=%d chunks=%d copy=%v", qb.tableName, len(rows), chunks, useCopy)
+
+	var total int64
+	for start := 0; start < len(rows); start += size {
+		end := min(start+size, len(rows))
+		chunk := rows[start:end]
+
+		var n int64
+		if useCopy {
+			if chunk, err = encodeRows(chunk); err != nil {
+				return 0, err
+			}
+			n, err = db.CopyFrom(ctx, pgx.Identifier(strings.Split(qb.tableName, ".")), qb.bulkColumns, pgx.CopyFromRows(chunk))
+			if err != nil {
+				return 0, fmt.Errorf("bulk copy failed: %w", err)
+			}
+		} else {
+			sub := *qb
+			sub.bulkRows = chunk
+			sql, args, err := sub.Build()
+			if err != nil {
+				return 0, err
+			}
+			if qb.returningColumns != nil {
+				var chunkTargets []reflect.Value
+				if end <= len(targets) {
+					chunkTargets = targets[start:end]
+				}
+				if n, err = q.queryReturning(ctx, db, sql, args, qb.bulkColumns, chunk, chunkTargets); err != nil {
+					return 0, err
+				}
+			} else {
+				result, err := db.Exec(ctx, sql, args...)
+				if err != nil {
+					return 0, fmt.Errorf("bulk insert failed: %w", err)
+				}
+				n = result.RowsAffected()
+			}
+		}
+		total += n
+	}
+
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return 0, fmt.Errorf("failed to commit bulk insert: %w", err)
+		}
+	}
+	return total, nil
+}
+
+// rowsPerStatement returns how many rows fit in one multi-VALUES insert under
+// the bind parameter limit, after the args of the ON CONFLICT clause
+func (qb *QueryBuilder) rowsPerStatement(rows [][]interface{}) (int, error) {
+	_, conflictArgs, err := qb.conflictClause(qb.bulkColumns, 1)
+	if err != nil {
+		return 0, err
+	}
+	perRow := 0
+	for _, row := range rows {
+		perRow = max(perRow, bindParams(row))
+	}
+	if perRow == 0 {
+		return len(rows), nil
+	}
+	budget := maxBindParams - bindParams(conflictArgs)
+	if budget < perRow {
+		return 0, fmt.Errorf("bulk insert into '%s': a row needs %d bind parameters but the ON CONFLICT clause leaves %d", qb.tableName, perRow, max(budget, 0))
+	}
+	return budget / perRow, nil
+}
+
+// rowsHaveExpression reports whether any cell is an Expression (which COPY can't send)
+func rowsHaveExpression(rows [][]interface{}) bool {
+	for _, row := range rows {
+		if hasExpression(row) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,409 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+// CascadeConfig controls how ondelete actions of soft keys (skey) are enforced
+// Hard keys (fkey) are enforced by the database and are not affected.
+type CascadeConfig struct {
+	BatchSize int  // max keys per IN list when touching dependent rows (0 = 1000)
+	Disabled  bool // ignore ondelete on soft keys (deletes never touch dependents)
+}
+
+var (
+	cascadeCfg   CascadeConfig
+	cascadeCfgMu sync.RWMutex
+)
+
+// SetCascadeConfig sets the global soft-key cascade configuration
+func SetCascadeConfig(cfg CascadeConfig) {
+	cascadeCfgMu.Lock()
+	defer cascadeCfgMu.Unlock()
+	cascadeCfg = cfg
+}
+
+func getCascadeConfig() CascadeConfig {
+	cascadeCfgMu.RLock()
+	defer cascadeCfgMu.RUnlock()
+	cfg := cascadeCfg
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BatchSize > maxBindParams {
+		cfg.BatchSize = maxBindParams
+	}
+	return cfg
+}
+
+// ErrRestrictViolation is matched (errors.Is) by a *RestrictError
+var ErrRestrictViolation = errors.New("delete restricted by dependent rows")
+
+// RestrictError reports a delete refused by an ondelete:restrict soft key
+type RestrictError struct {
+	Table     string // table being deleted from
+	Column    string // referenced column of Table
+	Dependent string // table holding the soft key
+	Key       string // soft key column of Dependent
+}
+
+func (e *RestrictError) Error() string {
+	return fmt.Sprintf("cannot delete from '%s': rows of '%s' still reference it through %s.%s -> %s.%s (ondelete:restrict)",
+		e.Table, e.Dependent, e.Dependent, e.Key, e.Table, e.Column)
+}
+
+// Is makes errors.Is(err, ErrRestrictViolation) match
+func (e *RestrictError) Is(target error) bool {
+	return target == ErrRestrictViolation
+}
+
+// CascadeResult is the outcome of one statement of a cascading delete on one shard
+type CascadeResult struct {
+	Table  string
+	Shard  string // shard name ("global" in global mode)
+	Action string // "DELETE" or "SET NULL"
+	Rows   int64
+}
+
+// CascadeReport lists the rows affected by a delete and its soft-key cascades
+// Dependents come first, in the order they were processed; the delete on the
+// table itself is last.
+type CascadeReport struct {
+	Results []CascadeResult
+}
+
+// Total returns the rows affected across all tables and shards
+func (r *CascadeReport) Total() int64 {
+	var n int64
+	for _, res := range r.Results {
+		n += res.Rows
+	}
+	return n
+}
+
+// Rows returns the rows affected in one table
+func (r *CascadeReport) Rows(table string) int64 {
+	var n int64
+	for _, res := range r.Results {
+		if res.Table == table {
+			n += res.Rows
+		}
+	}
+	return n
+}
+
+// ByShard returns the rows affected per shard
+func (r *CascadeReport) ByShard() map[string]int64 {
+	out := make(map[string]int64)
+	for _, res := range r.Results {
+		out[res.Shard] += res.Rows
+	}
+	return out
+}
+
+// dependent is a soft key referencing a table with an enforced ondelete action
+type dependent struct {
+	table     string // table holding the soft key
+	column    string // soft key column
+	refColumn string // referenced column of the parent table
+	action    string // "CASCADE", "SET NULL" or "RESTRICT"
+}
+
+// dependentsOf lists the soft keys that reference table with an enforced ondelete action
+func dependentsOf(table string) []dependent {
+	tables := registry.ListTables()
+	sort.Strings(tables)
+
+	var deps []dependent
+	for _, name := range tables {
+		tm, ok := registry.GetModel(name)
+		if !ok {
+			continue
+		}
+		for _, f := range tm.Fields {
+			refTable, refColumn, ok := strings.Cut(f.Skey, ".")
+			if !ok || refTable != table {
+				continue
+			}
+			action := strings.ToUpper(strings.TrimSpace(f.OnDelete))
+			switch action {
+			case "SETNULL", "SET_NULL":
+				action = "SET NULL"
+			case "CASCADE", "SET NULL", "RESTRICT":
+			default:
+				continue
+			}
+			deps = append(deps, dependent{table: name, column: f.Fieldname, refColumn: refColumn, action: action})
+		}
+	}
+	return deps
+}
+
+// hasSoftCascade reports whether a DELETE on q needs application-level cascading
+func (q *Query[T]) hasSoftCascade() bool {
+	if q.builder == nil || q.builder.queryType != "delete" || q.rawSQL != "" || q.table == "" {
+		return false
+	}
+	return !getCascadeConfig().Disabled && len(dependentsOf(q.table)) > 0
+}
+
+// ExecCascade runs a DELETE and enforces the ondelete actions of soft keys referencing the table
+// Dependents are found on whichever shard they live: cascade deletes them,
+// setnull clears the key, and restrict refuses the whole delete with a
+// *RestrictError. Every statement is planned (and every restrict checked)
+// before anything is modified. Changes on different shards are not atomic.
+// Usage:
+//
+//	report, err := norm.Table("users").Delete().Where("id = $1", id).ExecCascade(ctx)
+//	fmt.Println(report.ByShard())
+func (q *Query[T]) ExecCascade(ctx ...context.Context) (*CascadeReport, error) {
+	execCtx := context.Background()
+	if len(ctx) > 0 {
+		execCtx = ctx[0]
+	}
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.builder == nil || q.builder.queryType != "delete" {
+		return nil, fmt.Errorf("ExecCascade requires a DELETE query")
+	}
+
+	report, err := q.cascadeDependents(execCtx)
+	if err != nil {
+		return report, err
+	}
+
+	root := &Query[any]{builder: q.builder, table: q.table, tx: q.tx}
+	results, err := root.execPerShard(execCtx)
+	for i := range results {
+		results[i].Action = "DELETE"
+	}
+	report.Results = append(report.Results, results...)
+	return report, err
+}
+
+// cascadeDependents plans the soft-key cascades of a DELETE and applies them to
+// the dependent tables; the rows of q.table itself are left to the caller
+func (q *Query[T]) cascadeDependents(ctx context.Context) (*CascadeReport, error) {
+	c := &cascader{cfg: getCascadeConfig(), tx: q.tx, seen: make(map[string]bool)}
+	report := &CascadeReport{}
+	if c.cfg.Disabled {
+		return report, nil
+	}
+
+	root := &Query[any]{builder: q.builder, table: q.table, tx: q.tx}
+	if err := c.plan(ctx, root); err != nil {
+		return report, err
+	}
+
+	for _, op := range c.ops {
+		results, err := op.query.execPerShard(ctx)
+		for i := range results {
+			results[i].Action = op.action
+		}
+		report.Results = append(report.Results, results...)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// cascadeOp is one planned statement of a cascading delete
+type cascadeOp struct {
+	query  *Query[any]
+	action string
+}
+
+// cascader plans the statements of a cascading delete
+type cascader struct {
+	cfg  CascadeConfig
+	tx   *Tx
+	ops  []cascadeOp
+	seen map[string]bool // "table.column=value" keys already cascaded (guards cycles)
+}
+
+// plan adds the statements needed before the rows matched by del can be deleted
+// Dependents are planned depth-first so the deepest rows are changed first.
+func (c *cascader) plan(ctx context.Context, del *Query[any]) error {
+	deps := dependentsOf(del.table)
+	if len(deps) == 0 {
+		return nil
+	}
+
+	// Referenced values of the rows about to be deleted
+	var refCols []string
+	for _, d := range deps {
+		if !containsString(refCols, d.refColumn) {
+			refCols = append(refCols, d.refColumn)
+		}
+	}
+	sel := &Query[any]{tx: c.tx}
+	sel.Table(del.table)
+	sel.builder.queryType = "select"
+	sel.builder.columns = refCols
+	sel.builder.whereClause = del.builder.whereClause
+	sel.builder.whereArgs = del.builder.whereArgs
+	rows, err := sel.queryMaps(ctx)
+	if err != nil {
+		return fmt.Errorf("cascade: failed to read '%s': %w", del.table, err)
+	}
+
+	keys := make(map[string][]interface{})
+	for _, col := range refCols {
+		for _, row := range rows {
+			v := row[col]
+			if v == nil {
+				continue
+			}
+			k := fmt.Sprintf("%s.%s=%v", del.table, col, v)
+			if !c.seen[k] {
+				c.seen[k] = true
+				keys[col] = append(keys[col], v)
+			}
+		}
+	}
+
+	// Restrict first, so nothing is planned for a delete that will be refused
+	for _, d := range deps {
+		if d.action != "RESTRICT" {
+			continue
+		}
+		for _, batch := range batchValues(keys[d.refColumn], c.cfg.BatchSize) {
+			probe := &Query[any]{tx: c.tx}
+			probe.Table(d.table)
+			probe.builder.queryType = "select"
+			probe.builder.columns = []string{d.column}
+			probe.builder.limit = 1
+			probe.Where(In(d.column, batch))
+			found, err := probe.queryMaps(ctx)
+			if err != nil {
+				return fmt.Errorf("cascade: failed to check '%s': %w", d.table, err)
+			}
+			if len(found) > 0 {
+				return &RestrictError{Table: del.table, Column: d.refColumn, Dependent: d.table, Key: d.column}
+			}
+		}
+	}
+
+	for _, d := range deps {
+		for _, batch := range batchValues(keys[d.refColumn], c.cfg.BatchSize) {
+			op := &Query[any]{tx: c.tx}
+			op.Table(d.table)
+			switch d.action {
+			case "CASCADE":
+				op.builder.queryType = "delete"
+				op.Where(In(d.column, batch))
+				if err := c.plan(ctx, op); err != nil {
+					return err
+				}
+				c.ops = append(c.ops, cascadeOp{query: op, action: "DELETE"})
+			case "SET NULL":
+				op.builder.queryType = "update"
+				op.builder.updateFields = map[string]interface{}{d.column: nil}
+				op.Where(In(d.column, batch))
+				c.ops = append(c.ops, cascadeOp{query: op, action: "SET NULL"})
+			}
+		}
+	}
+	return nil
+}
+
+// execPerShard executes an UPDATE/DELETE and reports the rows affected on each shard
+func (q *Query[T]) execPerShard(ctx context.Context) ([]CascadeResult, error) {
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var shards []string
+	if tm := q.keyShardedTable(); tm != nil {
+		_, ok, err := q.shardKeyValue(tm)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			shard, err := q.shardForKey(tm)
+			if err != nil {
+				return nil, err
+			}
+			shards = []string{shard}
+		} else {
+			shards = tm.Sharding.Shards()
+			sort.Strings(shards)
+		}
+	}
+
+	// Single pool: global mode or a table placed on one shard
+	if len(shards) == 0 {
+		pool, err := q.getPool()
+		if err != nil {
+			return nil, err
+		}
+		db, err := q.conn(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		result, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("query execution failed on '%s': %w", q.table, err)
+		}
+		return []CascadeResult{{Table: q.table, Shard: q.shardLabel(), Rows: result.RowsAffected()}}, nil
+	}
+
+	var results []CascadeResult
+	for _, shard := range shards {
+		pool, err := q.poolOnShard(shard)
+		if err != nil {
+			return results, err
+		}
+		db, err := q.conn(ctx, pool)
+		if err != nil {
+			return results, err
+		}
+		result, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return results, fmt.Errorf("query execution failed on shard '%s': %w", shard, err)
+		}
+		results = append(results, CascadeResult{Table: q.table, Shard: shard, Rows: result.RowsAffected()})
+	}
+	return results, nil
+}
+
+// shardLabel names the shard a write on q.table goes to ("global" in global mode)
+func (q *Query[T]) shardLabel() string {
+	if registry.GetMode() != "shard" {
+		return "global"
+	}
+	if tm, ok := registry.GetModel(q.table); ok {
+		if shard, _, found := pickTableShard(tm, false); found {
+			return shard
+		}
+	}
+	return ""
+}
+
+// batchValues splits values into batches of at most size
+func batchValues(values []interface{}, size int) [][]interface{} {
+	var batches [][]interface{}
+	for start := 0; start < len(values); start += size {
+		batches = append(batches, values[start:min(start+size, len(values))])
+	}
+	return batches
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
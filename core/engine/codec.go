@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/skssmd/norm/core/utils"
+)
+
+// encodeArgs applies the Encode of registered types (see utils.RegisterType) to query args
+// args is returned as is when nothing needs encoding.
+func encodeArgs(args []interface{}) ([]interface{}, error) {
+	var out []interface{}
+	for i, a := range args {
+		v, encoded, err := encodeValue(a)
+		if err != nil {
+			return nil, fmt.Errorf("arg $%d: %w", i+1, err)
+		}
+		if encoded && out == nil {
+			out = append([]interface{}(nil), args...)
+		}
+		if encoded {
+			out[i] = v
+		}
+	}
+	if out == nil {
+		return args, nil
+	}
+	return out, nil
+}
+
+// encodeValue encodes a value of a registered type; encoded is false for other values
+// A nil pointer to a registered type stays NULL.
+func encodeValue(a interface{}) (v interface{}, encoded bool, err error) {
+	if a == nil {
+		return nil, false, nil
+	}
+	m, ok := utils.LookupType(reflect.TypeOf(a))
+	if !ok || m.Encode == nil {
+		return a, false, nil
+	}
+	rv := reflect.ValueOf(a)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, true, nil
+		}
+		rv = rv.Elem()
+	}
+	v, err = m.Encode(rv.Interface())
+	return v, true, err
+}
+
+// encodeRows encodes the values of bulk insert rows sent with COPY
+func encodeRows(rows [][]interface{}) ([][]interface{}, error) {
+	out := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		enc, err := encodeArgs(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		out[i] = enc
+	}
+	return out, nil
+}
+
+// decodeInto stores the result of a registered Decode in field
+func decodeInto(field reflect.Value, decode func(interface{}) (interface{}, error), value interface{}) error {
+	out, err := decode(value)
+	if err != nil || out == nil {
+		return err
+	}
+	v := reflect.ValueOf(out)
+	if v.Type() != field.Type() {
+		if !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("decoder returned %T for %s", out, field.Type())
+		}
+		v = v.Convert(field.Type())
+	}
+	field.Set(v)
+	return nil
+}
+
+// decodeField stores the result of a registered Decode in field, allocating a pointer field
+func decodeField(field reflect.Value, decode func(interface{}) (interface{}, error), value interface{}) error {
+	if field.Kind() != reflect.Ptr {
+		return decodeInto(field, decode, value)
+	}
+	p := reflect.New(field.Type().Elem())
+	if err := decodeInto(p.Elem(), decode, value); err != nil {
+		return err
+	}
+	field.Set(p)
+	return nil
+}
+
+// isRegistered reports whether t has a type mapping (and is a single column value)
+func isRegistered(t reflect.Type) bool {
+	_, ok := utils.LookupType(t)
+	return ok
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Expression is a SQL expression used in place of a value
+// It can be passed wherever a query takes a value: Update pairs, Set,
+// BulkInsert rows, DoUpdateSet/Where args and Cond helpers. The expression is
+// inlined where the value's placeholder would be and its own ? placeholders
+// are numbered with the rest of the statement.
+type Expression struct {
+	sql    string
+	args   []interface{}
+	column string // column assigned by Inc (used by Update without a key)
+	err    error
+}
+
+// Expr creates a SQL expression with ? placeholders for its args
+// Args may themselves be expressions. Write ?? for a literal ? such as the
+// JSONB operators ?, ?| and ?&.
+// Usage: Update("balance", norm.Expr("balance + ?", 10))
+func Expr(sql string, args ...interface{}) Expression {
+	return Expression{sql: sql, args: args}
+}
+
+// Col references another column (or "table.column")
+// Usage: Update("display_name", norm.Col("username"))
+func Col(name string) Expression {
+	if !identRe.MatchString(name) {
+		return Expression{err: fmt.Errorf("invalid column reference %q", name)}
+	}
+	return Expression{sql: name}
+}
+
+// Now is the database's current timestamp (NOW())
+func Now() Expression {
+	return Expression{sql: "NOW()"}
+}
+
+// Inc adds n to a column; it can stand in for a whole pair in Update
+// Usage: Update(norm.Inc("views", 1), "seen_at", norm.Now())
+func Inc(column string, n interface{}) Expression {
+	e := Expr(column+" + ?", n)
+	e.column = column
+	if !identRe.MatchString(column) {
+		e.err = fmt.Errorf("invalid column reference %q", column)
+	}
+	return e
+}
+
+// String returns the expression's SQL with ? placeholders
+func (e Expression) String() string {
+	return e.sql
+}
+
+// render writes the expression with each ? replaced by place(arg)
+// A ? inside literals, quoted identifiers, comments or dollar quotes is kept,
+// and ?? is written as a single ?.
+func (e Expression) render(place func(arg interface{}) (string, error)) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+
+	next := 0
+	out, err := rewriteSQL(e.sql, questionParams, func(string) (string, error) {
+		if next >= len(e.args) {
+			return "", fmt.Errorf("expression %q has more ? placeholders than args", e.sql)
+		}
+		next++
+		return place(e.args[next-1])
+	})
+	if err != nil {
+		return "", err
+	}
+	if next != len(e.args) {
+		return "", fmt.Errorf("expression %q has %d ? placeholders for %d args", e.sql, next, len(e.args))
+	}
+	return out, nil
+}
+
+// hasExpression reports whether any arg is an Expression
+func hasExpression(args []interface{}) bool {
+	for _, a := range args {
+		if _, ok := a.(Expression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bindParams counts the bind parameters args take once expressions are inlined
+// (an upper bound: a plain arg reused by several placeholders is counted once per arg)
+func bindParams(args []interface{}) int {
+	n := 0
+	for _, a := range args {
+		if e, ok := a.(Expression); ok {
+			n += bindParams(e.args)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// expandExpressions inlines Expression args into sql and renumbers the placeholders
+// Plain args keep one placeholder each (in order of first use); expressions are
+// rendered in parentheses at every placeholder that referenced them.
+func expandExpressions(sql string, args []interface{}) (string, []interface{}, error) {
+	if !hasExpression(args) {
+		return sql, args, nil
+	}
+
+	var out []interface{}
+	var place func(arg interface{}) (string, error)
+	place = func(arg interface{}) (string, error) {
+		if e, ok := arg.(Expression); ok {
+			inner, err := e.render(place)
+			if err != nil {
+				return "", err
+			}
+			return "(" + inner + ")", nil
+		}
+		out = append(out, arg)
+		return "$" + strconv.Itoa(len(out)), nil
+	}
+
+	assigned := make(map[int]string)
+	expanded, err := rewriteSQL(sql, positionalParams, func(token string) (string, error) {
+		n, _ := strconv.Atoi(token[1:])
+		if n < 1 || n > len(args) {
+			return "", fmt.Errorf("placeholder %s has no arg", token)
+		}
+		if _, isExpr := args[n-1].(Expression); isExpr {
+			return place(args[n-1])
+		}
+		if p, ok := assigned[n]; ok {
+			return p, nil
+		}
+		p, err := place(args[n-1])
+		assigned[n] = p
+		return p, err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return expanded, out, nil
+}
+
+// Set assigns a column of an Insert or Update; value may be an Expression
+// Usage:
+//
+//	norm.Table(&user).Insert().Set("created_at", norm.Now()).Exec(ctx)
+//	norm.Table("accounts").Update().Set("balance", norm.Expr("balance - ?", amount)).Where("id = $1", id).Exec(ctx)
+func (q *Query[T]) Set(column string, value interface{}) *Query[T] {
+	switch q.builder.queryType {
+	case "insert":
+		q.builder.insertFields[column] = value
+	default:
+		q.builder.queryType = "update"
+		if q.builder.updateFields == nil {
+			q.builder.updateFields = make(map[string]interface{})
+		}
+		q.builder.updateFields[column] = value
+	}
+	return q
+}
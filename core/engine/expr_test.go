@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestExpressionRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    Expression
+		want    string
+		wantErr bool
+	}{
+		{"args", Expr("GREATEST(?, ?)", 1, 2), "GREATEST($1, $2)", false},
+		{"nested", Expr("balance + ?", Expr("? * ?", 2, 3)), "balance + ($1 * $2)", false},
+		{"jsonb key", Expr("tags ?? ?", "vip"), "tags ? $1", false},
+		{"jsonb any and all", Expr("tags ??| ? OR tags ??& ?", "a", "b"), "tags ?| $1 OR tags ?& $2", false},
+		{"string literal", Expr("coalesce(?, 'why?')", 1), "coalesce($1, 'why?')", false},
+		{"quoted identifier", Expr(`"odd?col" + ?`, 1), `"odd?col" + $1`, false},
+		{"line comment", Expr("? -- really?\n", 1), "$1 -- really?\n", false},
+		{"block comment", Expr("? /* why? */", 1), "$1 /* why? */", false},
+		{"dollar quote", Expr("? || $$a?b$$", 1), "$1 || $$a?b$$", false},
+		{"cast", Expr("?::int", "1"), "$1::int", false},
+		{"too few args", Expr("? + ?", 1), "", true},
+		{"too many args", Expr("?", 1, 2), "", true},
+		{"invalid column", Col("a; DROP"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			var place func(arg interface{}) (string, error)
+			place = func(arg interface{}) (string, error) {
+				if e, ok := arg.(Expression); ok {
+					inner, err := e.render(place)
+					return "(" + inner + ")", err
+				}
+				n++
+				return "$" + strconv.Itoa(n), nil
+			}
+			got, err := tt.expr.render(place)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
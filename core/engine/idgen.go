@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+// idColumn is a column of q.table filled by an application-side generator
+type idColumn struct {
+	name string
+	gen  registry.IDGenerator
+}
+
+// idColumns returns the generated-key columns of q.table (idgen:stride is filled by the database)
+func (q *Query[T]) idColumns() []idColumn {
+	tm, ok := registry.GetModel(q.table)
+	if !ok {
+		return nil
+	}
+	var cols []idColumn
+	for _, f := range tm.Fields {
+		if f.IDGen == "" || f.IDGen == registry.IDGenStride {
+			continue
+		}
+		if gen, ok := registry.GetIDGenerator(f.IDGen); ok {
+			cols = append(cols, idColumn{name: f.Fieldname, gen: gen})
+		}
+	}
+	return cols
+}
+
+// idShard returns the shard a new row will be routed to, if it can be known before routing
+func (q *Query[T]) idShard(values map[string]interface{}) string {
+	if registry.GetMode() != "shard" {
+		return ""
+	}
+	tm, ok := registry.GetModel(q.table)
+	if !ok {
+		return ""
+	}
+	if tm.IsKeySharded() {
+		if key, ok := values[tm.ShardKey]; ok {
+			if shard, err := tm.Sharding.ShardFor(key); err == nil {
+				return shard
+			}
+		}
+		return ""
+	}
+	shard, _, _ := pickTableShard(tm, false)
+	return shard
+}
+
+// fillInsertIDs generates keys for an Insert whose generated columns are unset
+// The keys are written back to the model when it was passed by pointer.
+func (q *Query[T]) fillInsertIDs(model interface{}) {
+	cols := q.idColumns()
+	if len(cols) == 0 || q.builder.insertFields == nil {
+		return
+	}
+
+	elem := reflect.ValueOf(model)
+	for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+		elem = elem.Elem()
+	}
+
+	for _, c := range cols {
+		if v, ok := q.builder.insertFields[c.name]; ok && !isZeroValue(v) {
+			continue
+		}
+		id, err := q.nextID(c, q.builder.insertFields, elem)
+		if err != nil {
+			q.err = err
+			return
+		}
+		q.builder.insertFields[c.name] = id
+	}
+}
+
+// fillBulkIDs generates keys for the rows of a BulkInsert whose generated columns are unset
+// models is the slice passed to BulkInsert (nil in manual mode); its elements receive the keys.
+func (q *Query[T]) fillBulkIDs(models reflect.Value) {
+	cols := q.idColumns()
+	if len(cols) == 0 || len(q.builder.bulkRows) == 0 {
+		return
+	}
+
+	for _, c := range cols {
+		idx := -1
+		for i, col := range q.builder.bulkColumns {
+			if col == c.name {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			// Column left out (zero in the first model): add it to every row
+			q.builder.bulkColumns = append(q.builder.bulkColumns[:len(q.builder.bulkColumns):len(q.builder.bulkColumns)], c.name)
+			idx = len(q.builder.bulkColumns) - 1
+			for i, row := range q.builder.bulkRows {
+				q.builder.bulkRows[i] = append(row[:len(row):len(row)], nil)
+			}
+		}
+
+		for i, row := range q.builder.bulkRows {
+			if !isZeroValue(row[idx]) {
+				continue
+			}
+			values := make(map[string]interface{}, len(row))
+			for j, col := range q.builder.bulkColumns {
+				values[col] = row[j]
+			}
+			var elem reflect.Value
+			if models.IsValid() && i < models.Len() {
+				elem = models.Index(i)
+				for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+					elem = elem.Elem()
+				}
+			}
+			id, err := q.nextID(c, values, elem)
+			if err != nil {
+				q.err = err
+				return
+			}
+			row[idx] = id
+		}
+	}
+}
+
+// nextID generates a key and stores it in the model field mapped to the column (if settable)
+// The returned value has the field's type, so the column is written as the model declares it.
+func (q *Query[T]) nextID(c idColumn, values map[string]interface{}, elem reflect.Value) (interface{}, error) {
+	id, err := c.gen.NextID(q.table, q.idShard(values))
+	if err != nil {
+		return nil, fmt.Errorf("idgen for %s.%s: %w", q.table, c.name, err)
+	}
+	if !elem.IsValid() || elem.Kind() != reflect.Struct {
+		return id, nil
+	}
+	f := fieldByColumn(elem.Type(), c.name)
+	if f == nil {
+		return id, nil
+	}
+
+	field, err := f.Ensure(elem)
+	if err != nil {
+		return nil, fmt.Errorf("idgen for %s.%s: %w", q.table, c.name, err)
+	}
+	target := field.Type()
+	ptr := target.Kind() == reflect.Ptr
+	if ptr {
+		target = target.Elem()
+	}
+	v, err := convertID(id, target)
+	if err != nil {
+		return nil, fmt.Errorf("idgen for %s.%s: %w", q.table, c.name, err)
+	}
+	if ptr {
+		p := reflect.New(target)
+		p.Elem().Set(v)
+		v = p
+	}
+	if field.CanSet() {
+		field.Set(v)
+	}
+	return v.Interface(), nil
+}
+
+// convertID converts a generated key to the type of the model field
+// Numbers convert between integer kinds, strings to named string types, and
+// UUID strings to [16]byte.
+func convertID(id interface{}, target reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(id)
+	if v.Type().ConvertibleTo(target) && (v.Kind() == reflect.String) == (target.Kind() == reflect.String) {
+		return v.Convert(target), nil
+	}
+	if s, ok := id.(string); ok && target.Kind() == reflect.Array && target.Len() == 16 && target.Elem().Kind() == reflect.Uint8 {
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil || len(b) != 16 {
+			return reflect.Value{}, fmt.Errorf("cannot store %q in %s", s, target)
+		}
+		out := reflect.New(target).Elem()
+		reflect.Copy(out, reflect.ValueOf(b))
+		return out, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot store %T key in field of type %s", id, target)
+}
+
+// isZeroValue reports whether v is nil or its type's zero value
+func isZeroValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.IsZero() || (rv.Kind() == reflect.Ptr && rv.Elem().IsZero())
+}
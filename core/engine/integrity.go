@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skssmd/norm/core/registry"
+)
+
+// validateSoftKeys enables soft-key checks for every Insert, BulkInsert and Update
+var validateSoftKeys atomic.Bool
+
+// SetSoftKeyValidation turns soft-key existence checks on or off for all writes
+// Individual queries can opt in with ValidateSoftKeys.
+func SetSoftKeyValidation(enabled bool) {
+	validateSoftKeys.Store(enabled)
+}
+
+// ErrDanglingSoftKey is matched (errors.Is) by a *DanglingSoftKeyError
+var ErrDanglingSoftKey = errors.New("dangling soft key")
+
+// DanglingSoftKeyError is returned when a write references soft-key values that don't exist
+type DanglingSoftKeyError struct {
+	Table   string        // table being written
+	Column  string        // soft key column
+	Ref     string        // referenced "table.column"
+	Missing []interface{} // values with no referenced row
+}
+
+func (e *DanglingSoftKeyError) Error() string {
+	vals := make([]string, len(e.Missing))
+	for i, v := range e.Missing {
+		vals[i] = fmt.Sprint(v)
+	}
+	return fmt.Sprintf("dangling soft key %s.%s -> %s: no rows for %s", e.Table, e.Column, e.Ref, strings.Join(vals, ", "))
+}
+
+// Is makes errors.Is(err, ErrDanglingSoftKey) match
+func (e *DanglingSoftKeyError) Is(target error) bool {
+	return target == ErrDanglingSoftKey
+}
+
+// ValidateSoftKeys checks that every skey value written by this Insert, BulkInsert
+// or Update exists in the referenced table before executing
+// Each referenced table is checked with one batched lookup, routed to its shards.
+// Usage:
+//
+//	_, err := norm.Table(order).Insert().ValidateSoftKeys().Exec(ctx)
+//	var dangling *norm.DanglingSoftKeyError
+//	if errors.As(err, &dangling) {
+//	    fmt.Println(dangling.Missing)
+//	}
+func (q *Query[T]) ValidateSoftKeys() *Query[T] {
+	q.validateSoftKeys = true
+	return q
+}
+
+// softKeyRef groups the values written to the soft keys pointing at one referenced column
+type softKeyRef struct {
+	column    string // soft key column of q.table
+	refTable  string
+	refColumn string
+	uuid      bool // refColumn is a UUID column
+	values    []interface{}
+}
+
+// checkSoftKeys verifies the soft-key values of an insert/update when validation is enabled
+func (q *Query[T]) checkSoftKeys(ctx context.Context) error {
+	if !q.validateSoftKeys && !validateSoftKeys.Load() {
+		return nil
+	}
+	if q.builder == nil || q.rawSQL != "" || q.table == "" {
+		return nil
+	}
+	tm, ok := registry.GetModel(q.table)
+	if !ok {
+		return nil
+	}
+
+	var refs []*softKeyRef
+	for _, f := range tm.Fields {
+		refTable, refColumn, ok := strings.Cut(f.Skey, ".")
+		if !ok {
+			continue
+		}
+		values := q.writtenValues(f.Fieldname)
+		if len(values) > 0 {
+			refs = append(refs, &softKeyRef{column: f.Fieldname, refTable: refTable, refColumn: refColumn, uuid: isUUIDColumn(refTable, refColumn), values: values})
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	// One lookup per referenced table
+	byTable := make(map[string][]*softKeyRef)
+	var tables []string
+	for _, r := range refs {
+		if _, ok := byTable[r.refTable]; !ok {
+			tables = append(tables, r.refTable)
+		}
+		byTable[r.refTable] = append(byTable[r.refTable], r)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		found, err := q.lookupSoftKeys(ctx, table, byTable[table])
+		if err != nil {
+			return err
+		}
+		for _, r := range byTable[table] {
+			var missing []interface{}
+			for _, v := range r.values {
+				if !found[r.refColumn][softKeyText(v, r.uuid)] {
+					missing = append(missing, v)
+				}
+			}
+			if len(missing) > 0 {
+				return &DanglingSoftKeyError{Table: q.table, Column: r.column, Ref: r.refTable + "." + r.refColumn, Missing: missing}
+			}
+		}
+	}
+	return nil
+}
+
+// lookupSoftKeys returns, per referenced column, the values that exist in table
+// The values are read as text, so they compare equal to softKeyText of the written values.
+func (q *Query[T]) lookupSoftKeys(ctx context.Context, table string, refs []*softKeyRef) (map[string]map[string]bool, error) {
+	values := make(map[string][]interface{})
+	var columns []string
+	for _, r := range refs {
+		if _, ok := values[r.refColumn]; !ok {
+			columns = append(columns, r.refColumn)
+		}
+		values[r.refColumn] = append(values[r.refColumn], r.values...)
+	}
+
+	// Batches stay under the bind parameter limit; usually this is a single query
+	var conds [][]Cond
+	var batch []Cond
+	size := 0
+	for _, col := range columns {
+		for _, chunk := range batchValues(values[col], maxBindParams) {
+			if size+len(chunk) > maxBindParams {
+				conds, batch, size = append(conds, batch), nil, 0
+			}
+			batch = append(batch, In(col, chunk))
+			size += len(chunk)
+		}
+	}
+	conds = append(conds, batch)
+
+	found := make(map[string]map[string]bool)
+	for _, col := range columns {
+		found[col] = make(map[string]bool)
+	}
+	for _, c := range conds {
+		lookup := &Query[any]{tx: q.tx}
+		lookup.Table(table)
+		lookup.builder.queryType = "select"
+		lookup.builder.columns = make([]string, len(columns))
+		for i, col := range columns {
+			lookup.builder.columns[i] = fmt.Sprintf(`%s::text AS "%s"`, col, col)
+		}
+		lookup.Where(Or(c...))
+		rows, err := lookup.queryMaps(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("soft key check on '%s' failed: %w", table, err)
+		}
+		for _, row := range rows {
+			for _, col := range columns {
+				if v := row[col]; v != nil {
+					found[col][fmt.Sprint(v)] = true // already text
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// writtenValues returns the distinct non-NULL values the query writes to column
+func (q *Query[T]) writtenValues(column string) []interface{} {
+	var raw []interface{}
+	switch q.builder.queryType {
+	case "insert":
+		if v, ok := q.builder.insertFields[column]; ok {
+			raw = append(raw, v)
+		}
+	case "update":
+		if v, ok := q.builder.updateFields[column]; ok {
+			raw = append(raw, v)
+		}
+	case "bulkinsert":
+		for i, col := range q.builder.bulkColumns {
+			if col != column {
+				continue
+			}
+			for _, row := range q.builder.bulkRows {
+				if i < len(row) {
+					raw = append(raw, row[i])
+				}
+			}
+		}
+	}
+
+	var out []interface{}
+	seen := make(map[string]bool)
+	for _, v := range raw {
+		// Expressions are computed by the database and can't be checked up front
+		if _, isExpr := v.(Expression); isExpr {
+			continue
+		}
+		v, ok := derefValue(v)
+		if !ok {
+			continue
+		}
+		if k := fmt.Sprint(v); !seen[k] {
+			seen[k] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// softKeyText renders a written soft-key value the way Postgres casts it to text
+// UUIDs are written in lower-case hyphenated form whether given as 16 bytes or,
+// for UUID columns, as text in any accepted spelling.
+func softKeyText(v interface{}, uuid bool) string {
+	var b []byte
+	switch u := v.(type) {
+	case pgtype.UUID:
+		if u.Valid {
+			b = u.Bytes[:]
+		}
+	case string:
+		if uuid {
+			h, err := hex.DecodeString(strings.NewReplacer("-", "", "{", "", "}", "").Replace(u))
+			if err == nil && len(h) == 16 {
+				b = h
+			}
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Array && rv.Len() == 16 && rv.Type().Elem().Kind() == reflect.Uint8 {
+			b = make([]byte, 16)
+			reflect.Copy(reflect.ValueOf(b), rv)
+		}
+	}
+	if b == nil {
+		return fmt.Sprint(v)
+	}
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// isUUIDColumn reports whether column of the registered table has type UUID
+func isUUIDColumn(table, column string) bool {
+	tm, ok := registry.GetModel(table)
+	if !ok {
+		return false
+	}
+	for _, f := range tm.Fields {
+		if f.Fieldname == column {
+			return strings.EqualFold(f.Fieldtype, "UUID")
+		}
+	}
+	return false
+}
+
+// derefValue dereferences pointers; nil (NULL) values report false
+func derefValue(v interface{}) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	return keyValue(reflect.ValueOf(v))
+}
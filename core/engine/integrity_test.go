@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type namedUUID [16]byte
+
+func TestSoftKeyText(t *testing.T) {
+	id := [16]byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00}
+	const canonical = "550e8400-e29b-41d4-a716-446655440000"
+
+	tests := []struct {
+		name string
+		v    interface{}
+		uuid bool
+		want string
+	}{
+		{"int", int64(42), false, "42"},
+		{"string", "abc", false, "abc"},
+		{"bytes", id, true, canonical},
+		{"named bytes", namedUUID(id), true, canonical},
+		{"pgtype", pgtype.UUID{Bytes: id, Valid: true}, true, canonical},
+		{"canonical text", canonical, true, canonical},
+		{"upper case text", "550E8400-E29B-41D4-A716-446655440000", true, canonical},
+		{"braces without hyphens", "{550e8400e29b41d4a716446655440000}", true, canonical},
+		{"text column keeps case", "550E8400-E29B-41D4-A716-446655440000", false, "550E8400-E29B-41D4-A716-446655440000"},
+		{"invalid uuid text", "not-a-uuid", true, "not-a-uuid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := softKeyText(tt.v, tt.uuid); got != tt.want {
+				t.Errorf("softKeyText(%v) = %q, want %q", tt.v, got, tt.want)
+			}
+		})
+	}
+}
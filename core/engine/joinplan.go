@@ -0,0 +1,780 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+// joinTypes are the join semantics supported by both native and app-side joins
+var joinTypes = map[string]bool{"INNER": true, "LEFT": true, "RIGHT": true, "FULL": true}
+
+// JoinType sets how a table in the join chain is joined: "INNER" (default), "LEFT", "RIGHT" or "FULL"
+// The join is between the table and the rows already joined before it, so
+// LEFT keeps those rows when the table has no match and RIGHT keeps the
+// table's rows when nothing before it matches; missing columns are NULL.
+// Usage:
+//
+//	norm.Table("users", "id", "orders", "user_id").
+//	    JoinType("orders", "LEFT").
+//	    All(ctx, &rows)
+func (q *Query[T]) JoinType(table, joinType string) *Query[T] {
+	jc := q.joinContext
+	if jc == nil {
+		q.err = fmt.Errorf("JoinType(%q) requires a join query", table)
+		return q
+	}
+	// Accept "left", "LEFT JOIN" and "LEFT OUTER JOIN" alike
+	joinType = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(joinType)), " JOIN")
+	joinType = strings.TrimSuffix(joinType, " OUTER")
+	if !joinTypes[joinType] {
+		q.err = fmt.Errorf("unsupported join type %q", joinType)
+		return q
+	}
+
+	switch i := jc.lookup(table, len(jc.Tables)); {
+	case i == 0:
+		q.err = fmt.Errorf("JoinType: %q is the first table of the join", table)
+		return q
+	case i > 0:
+		for len(jc.Types) < len(jc.Tables) {
+			jc.Types = append(jc.Types, "INNER")
+		}
+		jc.Types[i] = joinType
+		return q
+	}
+	q.err = fmt.Errorf("JoinType: table %q is not part of the join", table)
+	return q
+}
+
+// WhereTable adds a WHERE condition that applies to one table of a join
+// The condition is evaluated against that table before joining: app-side
+// joins push it down to the table's own query (and shards), native joins
+// filter the table in a derived table. Columns are unqualified.
+// Usage:
+//
+//	norm.Table("users", "id", "orders", "user_id").
+//	    Where("users.status = $1", "active").
+//	    WhereTable("orders", Gt("total", 100)).
+//	    All(ctx, &rows)
+func (q *Query[T]) WhereTable(table string, condition interface{}, args ...interface{}) *Query[T] {
+	jc := q.joinContext
+	if jc == nil {
+		q.err = fmt.Errorf("WhereTable(%q) requires a join query", table)
+		return q
+	}
+	if jc.Filters == nil {
+		jc.Filters = make(map[string]*QueryBuilder)
+	}
+	qb, ok := jc.Filters[table]
+	if !ok {
+		qb = &QueryBuilder{tableName: table, whereArgs: []interface{}{}}
+		jc.Filters[table] = qb
+	}
+	qb.addWhere("AND", false, condition, args)
+	return q
+}
+
+// JoinOn adds an INNER JOIN with an explicit ON condition
+// The table may carry an alias ("orders o"). Placeholders in the condition are
+// numbered from $1 (or use a single P for :name parameters). When the tables
+// are not co-located, a single equality between two tables ("o.user_id = u.id")
+// is executed as an app-side join; other conditions require co-located tables.
+// Usage:
+//
+//	norm.Table("users u").
+//	    Select("u.fullname", "o.total").
+//	    JoinOn("orders o", "o.user_id = u.id AND o.status = $1", "paid").
+//	    All(ctx, &rows)
+func (q *Query[T]) JoinOn(table, condition string, args ...interface{}) *Query[T] {
+	return q.addJoin("INNER", table, condition, args)
+}
+
+// LeftJoin adds a LEFT JOIN with an explicit ON condition (see JoinOn)
+func (q *Query[T]) LeftJoin(table, condition string, args ...interface{}) *Query[T] {
+	return q.addJoin("LEFT", table, condition, args)
+}
+
+// RightJoin adds a RIGHT JOIN with an explicit ON condition (see JoinOn)
+func (q *Query[T]) RightJoin(table, condition string, args ...interface{}) *Query[T] {
+	return q.addJoin("RIGHT", table, condition, args)
+}
+
+// FullJoin adds a FULL JOIN with an explicit ON condition (see JoinOn)
+func (q *Query[T]) FullJoin(table, condition string, args ...interface{}) *Query[T] {
+	return q.addJoin("FULL", table, condition, args)
+}
+
+// CrossJoin adds a CROSS JOIN (every combination of rows)
+func (q *Query[T]) CrossJoin(table string) *Query[T] {
+	return q.addJoin("CROSS", table, "", nil)
+}
+
+// joinEqualityRe matches an ON condition that is a single "a.col = b.col"
+var joinEqualityRe = regexp.MustCompile(`^\s*(\w+)\.(\w+)\s*=\s*(\w+)\.(\w+)\s*$`)
+
+// addJoin appends a fluent join to the join chain, starting the chain if needed
+func (q *Query[T]) addJoin(kind, table, condition string, args []interface{}) *Query[T] {
+	if q.builder == nil {
+		q.err = fmt.Errorf("%s JOIN %s requires a table", kind, table)
+		return q
+	}
+	if kind != "CROSS" && strings.TrimSpace(condition) == "" {
+		q.err = fmt.Errorf("%s JOIN %s requires an ON condition", kind, table)
+		return q
+	}
+	condition, args, err := bindArgs(condition, args)
+	if err != nil {
+		q.err = err
+		return q
+	}
+
+	jc := q.joinContext
+	if jc == nil {
+		baseTable, baseAlias := splitAlias(q.builder.tableName)
+		jc = &JoinContext{
+			Tables:  []string{baseTable},
+			Keys:    []string{""},
+			Models:  []interface{}{q.builder.model},
+			Types:   []string{"INNER"},
+			Aliases: []string{baseAlias},
+		}
+		q.joinContext = jc
+	}
+
+	name, alias := splitAlias(table)
+	for len(jc.Ons) < len(jc.Tables) {
+		jc.Ons = append(jc.Ons, "")
+		jc.OnArgs = append(jc.OnArgs, nil)
+	}
+	jc.Tables = append(jc.Tables, name)
+	jc.Aliases = append(jc.Aliases, alias)
+	jc.Models = append(jc.Models, nil)
+	jc.Types = append(jc.Types, kind)
+	jc.Ons = append(jc.Ons, condition)
+	jc.OnArgs = append(jc.OnArgs, args)
+
+	// A plain key equality can also run as an app-side join
+	key := ""
+	if m := joinEqualityRe.FindStringSubmatch(condition); m != nil && len(args) == 0 {
+		n := len(jc.Tables) - 1
+		ref := jc.ref(n)
+		switch {
+		case (m[1] == ref || m[1] == name) && jc.lookup(m[3], n) >= 0:
+			key = m[2] + "=" + m[3] + "." + m[4]
+		case (m[3] == ref || m[3] == name) && jc.lookup(m[1], n) >= 0:
+			key = m[4] + "=" + m[1] + "." + m[2]
+		}
+	}
+	jc.Keys = append(jc.Keys, key)
+	return q
+}
+
+// splitAlias splits "orders o" or "orders AS o" into table and alias
+func splitAlias(table string) (string, string) {
+	fields := strings.Fields(table)
+	switch {
+	case len(fields) == 2:
+		return fields[0], fields[1]
+	case len(fields) == 3 && strings.EqualFold(fields[1], "AS"):
+		return fields[0], fields[2]
+	case len(fields) == 1:
+		return fields[0], ""
+	}
+	return strings.TrimSpace(table), ""
+}
+
+// joinType returns the join type of Tables[i]
+func (jc *JoinContext) joinType(i int) string {
+	if i < len(jc.Types) && jc.Types[i] != "" {
+		return jc.Types[i]
+	}
+	return "INNER"
+}
+
+// ref returns the name Tables[i] is referred to by in SQL and result keys (its alias, if any)
+func (jc *JoinContext) ref(i int) string {
+	if i < len(jc.Aliases) && jc.Aliases[i] != "" {
+		return jc.Aliases[i]
+	}
+	return jc.Tables[i]
+}
+
+// source returns Tables[i] as written in a FROM or JOIN ("orders o")
+func (jc *JoinContext) source(i int) string {
+	if i < len(jc.Aliases) && jc.Aliases[i] != "" {
+		return jc.Tables[i] + " " + jc.Aliases[i]
+	}
+	return jc.Tables[i]
+}
+
+// on returns the explicit ON condition of Tables[i], if any
+func (jc *JoinContext) on(i int) string {
+	if i < len(jc.Ons) {
+		return jc.Ons[i]
+	}
+	return ""
+}
+
+// lookup finds the first of Tables[:n] named name (by alias or table name)
+func (jc *JoinContext) lookup(name string, n int) int {
+	for i := 0; i < n; i++ {
+		if jc.ref(i) == name {
+			return i
+		}
+	}
+	for i := 0; i < n; i++ {
+		if jc.Tables[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// joinStep joins ref.column to leftRef.leftColumn, where leftRef is earlier in the chain
+// column is empty for CROSS joins and for ON conditions that aren't a single key equality.
+type joinStep struct {
+	index      int
+	table      string
+	ref        string
+	column     string
+	leftTable  string
+	leftRef    string
+	leftColumn string
+	kind       string
+}
+
+// steps resolves the join chain
+// Each table joins to the key of the table before it, unless its key names
+// another earlier table explicitly: "order_id=orders.id".
+func (jc *JoinContext) steps() ([]joinStep, error) {
+	if len(jc.Keys) < len(jc.Tables) {
+		return nil, fmt.Errorf("join requires a key for every table")
+	}
+
+	steps := make([]joinStep, 0, len(jc.Tables)-1)
+	for i := 1; i < len(jc.Tables); i++ {
+		step := joinStep{index: i, table: jc.Tables[i], ref: jc.ref(i), kind: jc.joinType(i)}
+		if step.kind == "CROSS" || jc.Keys[i] == "" {
+			steps = append(steps, step)
+			continue
+		}
+
+		local, target, explicit := strings.Cut(jc.Keys[i], "=")
+		step.column = strings.TrimSpace(local)
+		left := i - 1
+		if explicit {
+			name, column, ok := strings.Cut(strings.TrimSpace(target), ".")
+			if !ok {
+				return nil, fmt.Errorf("join key %q must reference table.column", jc.Keys[i])
+			}
+			if left = jc.lookup(name, i); left < 0 {
+				return nil, fmt.Errorf("join key %q references %q, which is not joined before %q", jc.Keys[i], name, step.ref)
+			}
+			step.leftColumn = column
+		} else {
+			prev, _, _ := strings.Cut(jc.Keys[left], "=")
+			if step.leftColumn = strings.TrimSpace(prev); step.leftColumn == "" {
+				return nil, fmt.Errorf("join key for %q has nothing to join to in %q", step.ref, jc.ref(left))
+			}
+		}
+		step.leftTable, step.leftRef = jc.Tables[left], jc.ref(left)
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// filterFor returns the scoped WHERE clause for Tables[i] (placeholders from $1)
+// Filters are looked up by alias first, then by table name.
+func (jc *JoinContext) filterFor(i int) (string, []interface{}, error) {
+	qb, ok := jc.Filters[jc.ref(i)]
+	if !ok {
+		qb, ok = jc.Filters[jc.Tables[i]]
+	}
+	if !ok {
+		return "", nil, nil
+	}
+	return qb.whereClause, qb.whereArgs, qb.err
+}
+
+// scopedSource returns Tables[i] as a FROM/JOIN source, as a derived table when it has scoped filters
+func (jc *JoinContext) scopedSource(i int) (string, []interface{}, error) {
+	clause, args, err := jc.filterFor(i)
+	if err != nil || clause == "" {
+		return jc.source(i), nil, err
+	}
+	return fmt.Sprintf("(SELECT * FROM %s WHERE %s) AS %s", jc.Tables[i], clause, jc.ref(i)), args, nil
+}
+
+// nativeJoinBuilder returns a copy of the query's builder with the join chain as SQL JOINs
+// Tables with scoped filters become derived tables so the filter applies before joining.
+func (q *Query[T]) nativeJoinBuilder(steps []joinStep) (*QueryBuilder, error) {
+	jc := q.joinContext
+	b := *q.builder
+	b.joins = append([]JoinDefinition{}, q.builder.joins...)
+
+	from, args, err := jc.scopedSource(0)
+	if err != nil {
+		return nil, err
+	}
+	b.tableName, b.fromArgs = from, args
+
+	for _, s := range steps {
+		source, args, err := jc.scopedSource(s.index)
+		if err != nil {
+			return nil, err
+		}
+		var on string
+		switch {
+		case s.kind == "CROSS":
+		case jc.on(s.index) != "":
+			// Explicit ON: its placeholders follow the derived table's
+			on = shiftPlaceholders(jc.on(s.index), len(args)+1)
+			args = append(append([]interface{}{}, args...), jc.OnArgs[s.index]...)
+		default:
+			on = fmt.Sprintf("%s.%s = %s.%s", s.leftRef, s.leftColumn, s.ref, s.column)
+		}
+		b.joins = append(b.joins, JoinDefinition{Table: source, On: on, Type: s.kind, Args: args})
+	}
+	return &b, nil
+}
+
+// qualifiedColumns aliases the selected columns as "ref.column" so native join
+// rows carry the same qualified names as app-side joined rows
+// * and ref.* are expanded from the registered models; expressions and
+// aliased columns are kept as written.
+func (jc *JoinContext) qualifiedColumns(columns []string) []string {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	var out []string
+	expand := func(i int) {
+		ref := jc.ref(i)
+		tm, ok := registry.GetModel(jc.Tables[i])
+		if !ok {
+			out = append(out, ref+".*")
+			return
+		}
+		for _, f := range tm.Fields {
+			out = append(out, fmt.Sprintf(`%s.%s AS "%s.%s"`, ref, f.Fieldname, ref, f.Fieldname))
+		}
+	}
+
+	for _, col := range columns {
+		c := strings.TrimSpace(col)
+		switch {
+		case c == "*":
+			for i := range jc.Tables {
+				expand(i)
+			}
+		case strings.HasSuffix(c, ".*") && jc.lookup(strings.TrimSuffix(c, ".*"), len(jc.Tables)) >= 0:
+			expand(jc.lookup(strings.TrimSuffix(c, ".*"), len(jc.Tables)))
+		case strings.ContainsAny(c, " (\"") || !strings.Contains(c, "."):
+			out = append(out, col)
+		default:
+			out = append(out, fmt.Sprintf(`%s AS "%s"`, c, c))
+		}
+	}
+	return out
+}
+
+// executeAppSideJoin executes a join by fetching each table from its own pool or
+// shards and merging the rows in memory.
+// The chain is walked left to right: every step queries the next table with
+// its scoped filters, restricted to the join keys seen so far (INNER/LEFT),
+// or unrestricted (RIGHT/FULL) so unmatched rows can be kept.
+func (q *Query[T]) executeAppSideJoin(ctx context.Context, dest interface{}, singleRow bool) error {
+	debugLog("Executing App-Side Join (Distributed/Skey)")
+
+	jc := q.joinContext
+	steps, err := jc.steps()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range steps {
+		if s.kind != "CROSS" && s.column == "" {
+			return fmt.Errorf("join ON %q can't run across shards: app-side joins need a single column equality", jc.on(s.index))
+		}
+	}
+
+	// Cache key covers the chain, join types and every filter
+	cacheQuery := fmt.Sprintf("JOIN:%s|%s|%s|%s|%s|%s|%d|%d", strings.Join(jc.Tables, ","), strings.Join(jc.Aliases, ","), strings.Join(jc.Keys, ","),
+		strings.Join(jc.Types, ","), q.builder.whereClause, q.builder.orderBy, q.builder.limit, q.builder.offset)
+	cacheArgs := append([]interface{}{}, q.builder.whereArgs...)
+	for i := range jc.Tables {
+		clause, args, err := jc.filterFor(i)
+		if err != nil {
+			return err
+		}
+		if clause != "" {
+			cacheQuery += "|" + jc.ref(i) + ":" + clause
+			cacheArgs = append(cacheArgs, args...)
+		}
+	}
+
+	// Check cache before executing expensive join
+	if cachedData, hit, err := q.checkCache(ctx, cacheQuery, cacheArgs); err != nil {
+		// Cache check errors are silently ignored (cache is optional)
+	} else if hit {
+		if dest != nil {
+			// Joined results are cached as the populated destination
+			return json.Unmarshal(cachedData, dest)
+		}
+		var cachedResults []map[string]interface{}
+		if err := json.Unmarshal(cachedData, &cachedResults); err != nil {
+			return fmt.Errorf("failed to unmarshal cached data: %w", err)
+		}
+		if IsDebugMode() {
+			q.printResults(cachedResults, true) // true = from cache
+		}
+		return nil
+	}
+
+	cols := q.joinColumns(steps)
+	var partialErr error
+
+	// 1. Fetch the first table with the main WHERE and its scoped filters
+	base := jc.ref(0)
+	q1 := *q
+	q1.joinContext = nil
+	b1 := *q.builder
+	b1.tableName = jc.source(0)
+	b1.joins = nil
+	b1.columns = cols[base]
+	b1.orderBy, b1.limit, b1.offset = "", 0, 0
+	q1.builder = &b1
+	if clause, args, _ := jc.filterFor(0); clause != "" {
+		b1.addWhere("AND", false, Clause(clause, args...), nil)
+	}
+
+	baseRows, err := q1.queryMaps(ctx)
+	if err != nil && !isPartial(err) {
+		return fmt.Errorf("failed to fetch %s: %w", base, err)
+	}
+	if err != nil {
+		partialErr = err
+	}
+
+	joined := make([]map[string]interface{}, 0, len(baseRows))
+	for _, row := range baseRows {
+		joined = append(joined, qualifyRow(base, row))
+	}
+	columnsOf := map[string][]string{base: rowColumns(base, jc.Tables[0], baseRows, cols[base])}
+
+	// 2. Walk the chain
+	for _, s := range steps {
+		rows, err := q.fetchJoinStep(ctx, s, joined, cols[s.ref])
+		if err != nil && !isPartial(err) {
+			return fmt.Errorf("failed to fetch %s: %w", s.ref, err)
+		}
+		if err != nil {
+			partialErr = err
+		}
+		columnsOf[s.ref] = rowColumns(s.ref, s.table, rows, cols[s.ref])
+		joined = mergeJoinStep(joined, rows, s, columnsOf)
+	}
+
+	// 3. ORDER BY / LIMIT / OFFSET apply to the joined rows
+	refs := make([]string, len(jc.Tables))
+	for i := range refs {
+		refs[i] = jc.ref(i)
+	}
+	order, err := joinOrder(q.builder.orderBy, refs, joined)
+	if err == nil {
+		err = sortMaps(joined, order)
+	}
+	if err != nil {
+		return err
+	}
+	limit := q.builder.limit
+	if singleRow {
+		limit = 1
+	}
+	joined = windowMaps(joined, limit, q.builder.offset)
+
+	if dest != nil {
+		if len(joined) == 0 && singleRow && partialErr != nil {
+			return partialErr
+		}
+		if err := scanMapsToDest(joined, dest, q.scanOptions()); err != nil {
+			return err
+		}
+		// Cache the POPULATED struct (dest) instead of the raw maps, so the cached JSON
+		// matches the struct fields rather than internal keys (users.name, orders.total)
+		if partialErr == nil {
+			_ = q.setCache(ctx, cacheQuery, cacheArgs, dest)
+		}
+		return partialErr
+	}
+
+	if partialErr == nil {
+		_ = q.setCache(ctx, cacheQuery, cacheArgs, joined)
+	}
+	if IsDebugMode() {
+		q.printResults(joined, false)
+	}
+	return partialErr
+}
+
+// fetchJoinStep queries the rows of the step's table that can join the current rows
+// CROSS joins fetch every row of the table (after its scoped filters).
+func (q *Query[T]) fetchJoinStep(ctx context.Context, s joinStep, joined []map[string]interface{}, columns []string) ([]map[string]interface{}, error) {
+	restrict := s.kind == "INNER" || s.kind == "LEFT"
+
+	var keys []interface{}
+	if restrict {
+		seen := make(map[string]bool)
+		for _, row := range joined {
+			val := row[s.leftRef+"."+s.leftColumn]
+			if val == nil {
+				continue
+			}
+			k := fmt.Sprintf("%v", val)
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, val)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, nil // Nothing can match
+		}
+	}
+
+	clause, args, err := q.joinContext.filterFor(s.index)
+	if err != nil {
+		return nil, err
+	}
+
+	query := func(keys []interface{}) ([]map[string]interface{}, error) {
+		sub := &Query[any]{tx: q.tx, allowPartial: q.allowPartial}
+		sub.Table(s.table)
+		sub.builder.tableName = q.joinContext.source(s.index)
+		sub.builder.columns = columns
+		sub.builder.queryType = "select"
+		if clause != "" {
+			sub.Where(Clause(clause, args...))
+		}
+		if keys != nil {
+			sub.Where(In(s.ref+"."+s.column, keys))
+		}
+		return sub.queryMaps(ctx)
+	}
+
+	if !restrict {
+		return query(nil)
+	}
+
+	// Keep each IN list under the bind parameter limit
+	chunk := maxBindParams - len(args)
+	var rows []map[string]interface{}
+	var partialErr error
+	for start := 0; start < len(keys); start += chunk {
+		part, err := query(keys[start:min(start+chunk, len(keys))])
+		if err != nil && !isPartial(err) {
+			return nil, err
+		}
+		if err != nil {
+			partialErr = err
+		}
+		rows = append(rows, part...)
+	}
+	return rows, partialErr
+}
+
+// mergeJoinStep joins the step's rows onto the current rows with the step's semantics
+func mergeJoinStep(joined, rows []map[string]interface{}, s joinStep, columnsOf map[string][]string) []map[string]interface{} {
+	if s.kind == "CROSS" {
+		out := make([]map[string]interface{}, 0, len(joined)*len(rows))
+		for _, left := range joined {
+			for _, row := range rows {
+				merged := qualifyRow(s.ref, row)
+				for k, v := range left {
+					merged[k] = v
+				}
+				out = append(out, merged)
+			}
+		}
+		return out
+	}
+
+	byKey := make(map[string][]int)
+	for i, row := range rows {
+		if val := row[s.column]; val != nil {
+			k := fmt.Sprintf("%v", val)
+			byKey[k] = append(byKey[k], i)
+		}
+	}
+
+	matched := make([]bool, len(rows))
+	out := make([]map[string]interface{}, 0, len(joined))
+	for _, left := range joined {
+		var hits []int
+		if val := left[s.leftRef+"."+s.leftColumn]; val != nil {
+			hits = byKey[fmt.Sprintf("%v", val)]
+		}
+		for _, i := range hits {
+			matched[i] = true
+			merged := make(map[string]interface{}, len(left)+len(rows[i]))
+			for k, v := range left {
+				merged[k] = v
+			}
+			for k, v := range qualifyRow(s.ref, rows[i]) {
+				merged[k] = v
+			}
+			out = append(out, merged)
+		}
+		if len(hits) == 0 && (s.kind == "LEFT" || s.kind == "FULL") {
+			merged := make(map[string]interface{}, len(left)+len(columnsOf[s.table]))
+			for k, v := range left {
+				merged[k] = v
+			}
+			for _, c := range columnsOf[s.ref] {
+				merged[c] = nil
+			}
+			out = append(out, merged)
+		}
+	}
+
+	if s.kind == "RIGHT" || s.kind == "FULL" {
+		for i, row := range rows {
+			if matched[i] {
+				continue
+			}
+			merged := qualifyRow(s.ref, row)
+			for ref, cols := range columnsOf {
+				if ref == s.ref {
+					continue
+				}
+				for _, c := range cols {
+					merged[c] = nil
+				}
+			}
+			out = append(out, merged)
+		}
+	}
+	return out
+}
+
+// joinColumns splits the selected columns by table ref ("o.total" belongs to orders o,
+// unqualified columns to the first table) and adds the join keys each table needs
+// A table without selected columns fetches all of them.
+func (q *Query[T]) joinColumns(steps []joinStep) map[string][]string {
+	jc := q.joinContext
+	cols := make(map[string][]string)
+	for _, col := range q.builder.columns {
+		owner := jc.ref(0)
+		if name, _, ok := strings.Cut(col, "."); ok {
+			if i := jc.lookup(name, len(jc.Tables)); i >= 0 {
+				owner = jc.ref(i)
+			}
+		}
+		cols[owner] = append(cols[owner], col)
+	}
+
+	ensure := func(ref, column string) {
+		if len(cols[ref]) == 0 || column == "" {
+			return
+		}
+		for _, c := range cols[ref] {
+			if c == column || c == ref+"."+column {
+				return
+			}
+		}
+		cols[ref] = append(cols[ref], ref+"."+column)
+	}
+	for _, s := range steps {
+		ensure(s.leftRef, s.leftColumn)
+		ensure(s.ref, s.column)
+	}
+
+	for i := range jc.Tables {
+		if ref := jc.ref(i); len(cols[ref]) == 0 {
+			cols[ref] = []string{"*"}
+		}
+	}
+	return cols
+}
+
+// qualifyRow prefixes a row's keys with the table ref
+func qualifyRow(ref string, row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if strings.Contains(k, ".") {
+			out[k] = v
+		} else {
+			out[ref+"."+k] = v
+		}
+	}
+	return out
+}
+
+// rowColumns lists the qualified columns of a table's rows, used to NULL-fill unmatched rows
+// Without rows, the selected columns (or the registered model's fields) are used.
+func rowColumns(ref, table string, rows []map[string]interface{}, selected []string) []string {
+	var cols []string
+	if len(rows) > 0 {
+		for k := range rows[0] {
+			if !strings.Contains(k, ".") {
+				k = ref + "." + k
+			}
+			cols = append(cols, k)
+		}
+		return cols
+	}
+
+	if len(selected) == 1 && selected[0] == "*" {
+		if tm, ok := registry.GetModel(table); ok {
+			for _, f := range tm.Fields {
+				cols = append(cols, ref+"."+f.Fieldname)
+			}
+		}
+		return cols
+	}
+
+	for _, c := range selected {
+		name := c
+		if i := strings.LastIndex(strings.ToLower(name), " as "); i >= 0 {
+			name = strings.TrimSpace(name[i+4:])
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		cols = append(cols, ref+"."+strings.Trim(name, `"`))
+	}
+	return cols
+}
+
+// joinOrder resolves ORDER BY terms against the qualified keys of joined rows
+// Unqualified columns match the first table in the chain that has them.
+func joinOrder(orderBy string, refs []string, rows []map[string]interface{}) ([]orderTerm, error) {
+	if orderBy == "" {
+		return nil, nil
+	}
+	var terms []orderTerm
+	for _, part := range strings.Split(orderBy, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		t, err := parseOrderTerm(part)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			if _, ok := rows[0][t.column]; !ok {
+				for _, ref := range refs {
+					if _, ok := rows[0][ref+"."+t.column]; ok {
+						t.column = ref + "." + t.column
+						break
+					}
+				}
+			}
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+func TestSplitAlias(t *testing.T) {
+	tests := []struct {
+		in           string
+		table, alias string
+	}{
+		{"orders", "orders", ""},
+		{" orders ", "orders", ""},
+		{"orders o", "orders", "o"},
+		{"orders AS o", "orders", "o"},
+		{"orders as o", "orders", "o"},
+		{"public.orders o", "public.orders", "o"},
+		{"orders o extra", "orders o extra", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			table, alias := splitAlias(tt.in)
+			if table != tt.table || alias != tt.alias {
+				t.Errorf("splitAlias(%q) = %q, %q; want %q, %q", tt.in, table, alias, tt.table, tt.alias)
+			}
+		})
+	}
+}
+
+func TestJoinContextSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		jc      JoinContext
+		want    []joinStep
+		wantErr bool
+	}{
+		{
+			name: "chain of keys",
+			jc: JoinContext{
+				Tables: []string{"users", "orders", "items"},
+				Keys:   []string{"id", "user_id", "order_id"},
+			},
+			want: []joinStep{
+				{index: 1, table: "orders", ref: "orders", column: "user_id", leftTable: "users", leftRef: "users", leftColumn: "id", kind: "INNER"},
+				{index: 2, table: "items", ref: "items", column: "order_id", leftTable: "orders", leftRef: "orders", leftColumn: "user_id", kind: "INNER"},
+			},
+		},
+		{
+			name: "explicit key to an earlier table",
+			jc: JoinContext{
+				Tables: []string{"users", "orders", "profiles"},
+				Keys:   []string{"id", "user_id", "user_id=users.id"},
+				Types:  []string{"INNER", "LEFT", "RIGHT"},
+			},
+			want: []joinStep{
+				{index: 1, table: "orders", ref: "orders", column: "user_id", leftTable: "users", leftRef: "users", leftColumn: "id", kind: "LEFT"},
+				{index: 2, table: "profiles", ref: "profiles", column: "user_id", leftTable: "users", leftRef: "users", leftColumn: "id", kind: "RIGHT"},
+			},
+		},
+		{
+			name: "aliases",
+			jc: JoinContext{
+				Tables:  []string{"users", "orders"},
+				Keys:    []string{"", "user_id=u.id"},
+				Types:   []string{"INNER", "FULL"},
+				Aliases: []string{"u", "o"},
+			},
+			want: []joinStep{
+				{index: 1, table: "orders", ref: "o", column: "user_id", leftTable: "users", leftRef: "u", leftColumn: "id", kind: "FULL"},
+			},
+		},
+		{
+			name: "cross and non-key ON",
+			jc: JoinContext{
+				Tables: []string{"users", "regions", "orders"},
+				Keys:   []string{"", "", ""},
+				Types:  []string{"INNER", "CROSS", "LEFT"},
+			},
+			want: []joinStep{
+				{index: 1, table: "regions", ref: "regions", kind: "CROSS"},
+				{index: 2, table: "orders", ref: "orders", kind: "LEFT"},
+			},
+		},
+		{
+			name:    "missing keys",
+			jc:      JoinContext{Tables: []string{"users", "orders"}, Keys: []string{"id"}},
+			wantErr: true,
+		},
+		{
+			name:    "explicit key without column",
+			jc:      JoinContext{Tables: []string{"users", "orders"}, Keys: []string{"id", "user_id=users"}},
+			wantErr: true,
+		},
+		{
+			name:    "explicit key to a later table",
+			jc:      JoinContext{Tables: []string{"users", "orders", "items"}, Keys: []string{"id", "user_id=items.id", "order_id"}},
+			wantErr: true,
+		},
+		{
+			name:    "nothing to join to",
+			jc:      JoinContext{Tables: []string{"users", "orders"}, Keys: []string{"", "user_id"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.jc.steps()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("steps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("steps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type joinplanAccount struct {
+	ID   int `norm:"pk"`
+	Name string
+}
+
+func TestQualifiedColumns(t *testing.T) {
+	registry.Table(joinplanAccount{}, "joinplan_accounts")
+	jc := &JoinContext{
+		Tables:  []string{"joinplan_accounts", "joinplan_unregistered"},
+		Aliases: []string{"a", ""},
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    []string
+	}{
+		{"default star", nil, []string{`a.id AS "a.id"`, `a.name AS "a.name"`, "joinplan_unregistered.*"}},
+		{"table star by alias", []string{"a.*"}, []string{`a.id AS "a.id"`, `a.name AS "a.name"`}},
+		{"table star by name", []string{"joinplan_accounts.*"}, []string{`a.id AS "a.id"`, `a.name AS "a.name"`}},
+		{"qualified column", []string{"a.name"}, []string{`a.name AS "a.name"`}},
+		{"unqualified column", []string{"name"}, []string{"name"}},
+		{"aliased column", []string{"a.name AS n"}, []string{"a.name AS n"}},
+		{"expression", []string{"count(a.id)"}, []string{"count(a.id)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jc.qualifiedColumns(tt.columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qualifiedColumns(%q) = %q, want %q", tt.columns, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBindParams is the most bind parameters Postgres accepts in one statement
+const maxBindParams = 65535
+
+// P holds named query parameters, referenced in SQL as :name
+// Usage: Where("email = :email AND status = :status", P{"email": e, "status": "active"})
+type P map[string]interface{}
+
+// paramStyle selects the placeholders rewriteSQL passes to repl
+type paramStyle int
+
+const (
+	positionalParams paramStyle = iota // $1, $2, ...
+	namedParams                        // $1, $2, ... and :name
+	questionParams                     // ? (as in Expr); ?? is a literal ?
+)
+
+// rewriteSQL walks query and replaces placeholders with the result of repl.
+// Which placeholders are passed to repl depends on style. String literals,
+// quoted identifiers, comments and dollar-quoted bodies are copied untouched,
+// and "::" casts are never mistaken for parameters.
+func rewriteSQL(query string, style paramStyle, repl func(token string) (string, error)) (string, error) {
+	var out strings.Builder
+	out.Grow(len(query) + 8)
+	n := len(query)
+
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '\'':
+			// E'...' strings allow backslash escapes
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i < 2 || !isIdentByte(query[i-2]))
+			end := quotedEnd(query, i, '\'', escapes)
+			out.WriteString(query[i:end])
+			i = end
+
+		case c == '"':
+			end := quotedEnd(query, i, '"', false)
+			out.WriteString(query[i:end])
+			i = end
+
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = n
+			} else {
+				end += i + 1
+			}
+			out.WriteString(query[i:end])
+			i = end
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := blockCommentEnd(query, i)
+			out.WriteString(query[i:end])
+			i = end
+
+		case c == '$' && (i == 0 || !isIdentByte(query[i-1])):
+			j := i + 1
+			for j < n && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+1 && (j == n || !isIdentByte(query[j])) && style != questionParams {
+				r, err := repl(query[i:j])
+				if err != nil {
+					return "", err
+				}
+				out.WriteString(r)
+				i = j
+				continue
+			}
+			if tag, ok := dollarTag(query, i); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					end = n
+				} else {
+					end += i + 2*len(tag)
+				}
+				out.WriteString(query[i:end])
+				i = end
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == ':' && i+1 < n && query[i+1] == ':':
+			out.WriteString("::")
+			i += 2
+
+		case c == '?' && style == questionParams:
+			if i+1 < n && query[i+1] == '?' {
+				out.WriteByte('?') // ?? escapes the JSONB operators ?, ?| and ?&
+				i += 2
+				continue
+			}
+			r, err := repl("?")
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(r)
+			i++
+
+		case c == ':' && style == namedParams && i+1 < n && isIdentStart(query[i+1]) && (i == 0 || !isIdentByte(query[i-1])):
+			j := i + 1
+			for j < n && isIdentByte(query[j]) {
+				j++
+			}
+			r, err := repl(query[i:j])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(r)
+			i = j
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// quotedEnd returns the index just past the quote closing the literal that starts at i
+// A doubled quote is an escaped quote. Unterminated literals run to the end.
+func quotedEnd(query string, i int, quote byte, backslashEscapes bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslashEscapes {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// blockCommentEnd returns the index just past the (possibly nested) comment starting at i
+func blockCommentEnd(query string, i int) int {
+	depth := 0
+	for j := i; j+1 < len(query); j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*':
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// dollarTag returns the $tag$ delimiter starting at i ("$$" for an empty tag)
+func dollarTag(query string, i int) (string, bool) {
+	j := i + 1
+	if j < len(query) && isIdentStart(query[j]) {
+		for j < len(query) && isIdentByte(query[j]) {
+			j++
+		}
+	}
+	if j < len(query) && query[j] == '$' {
+		return query[i : j+1], true
+	}
+	return "", false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentByte(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// shiftPlaceholders renumbers $1, $2, etc. to start from startIndex
+func shiftPlaceholders(query string, startIndex int) string {
+	if startIndex == 1 {
+		return query
+	}
+	out, _ := rewriteSQL(query, positionalParams, func(token string) (string, error) {
+		n, _ := strconv.Atoi(token[1:])
+		return "$" + strconv.Itoa(n+startIndex-1), nil
+	})
+	return out
+}
+
+// compileNamed replaces :name parameters with $1, $2, ... and returns the positional args
+// A name used several times binds to a single placeholder.
+func compileNamed(query string, params map[string]interface{}) (string, []interface{}, error) {
+	index := make(map[string]int)
+	var args []interface{}
+
+	out, err := rewriteSQL(query, namedParams, func(token string) (string, error) {
+		if token[0] == '$' {
+			return "", fmt.Errorf("cannot mix positional %s with named parameters in %q", token, query)
+		}
+		name := token[1:]
+		if n, ok := index[name]; ok {
+			return "$" + strconv.Itoa(n), nil
+		}
+		v, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("missing value for named parameter :%s", name)
+		}
+		args = append(args, v)
+		index[name] = len(args)
+		return "$" + strconv.Itoa(len(args)), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return out, args, nil
+}
+
+// bindArgs resolves the args of a hand-written SQL fragment
+// A single P argument compiles :name parameters into positional ones;
+// anything else is used as positional args as-is.
+func bindArgs(query string, args []interface{}) (string, []interface{}, error) {
+	if len(args) == 1 {
+		if p, ok := args[0].(P); ok {
+			return compileNamed(query, p)
+		}
+	}
+	return query, args, nil
+}
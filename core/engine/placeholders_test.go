@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriteSQL(t *testing.T) {
+	// repl marks every placeholder it is handed
+	repl := func(token string) (string, error) { return "<" + token + ">", nil }
+
+	tests := []struct {
+		name  string
+		query string
+		style paramStyle
+		want  string
+	}{
+		{"positional", "a = $1 AND b = $12", positionalParams, "a = <$1> AND b = <$12>"},
+		{"named ignored", "a = :x", positionalParams, "a = :x"},
+		{"named", "a = :x AND b = :y_2", namedParams, "a = <:x> AND b = <:y_2>"},
+		{"cast", "a::int = :x::text", namedParams, "a::int = <:x>::text"},
+		{"string literal", "a = '$1 :x' AND b = $1", namedParams, "a = '$1 :x' AND b = <$1>"},
+		{"doubled quote", "a = 'it''s $1' OR b = $2", positionalParams, "a = 'it''s $1' OR b = <$2>"},
+		{"escape string", `a = E'\' $1' AND b = $2`, positionalParams, `a = E'\' $1' AND b = <$2>`},
+		{"quoted identifier", `"$1" = $1`, positionalParams, `"$1" = <$1>`},
+		{"line comment", "a = $1 -- $2\nAND b = $3", positionalParams, "a = <$1> -- $2\nAND b = <$3>"},
+		{"block comment", "a = $1 /* $2 /* $3 */ */ AND b = $4", positionalParams, "a = <$1> /* $2 /* $3 */ */ AND b = <$4>"},
+		{"dollar quote", "$$ $1 $$ || $1", positionalParams, "$$ $1 $$ || <$1>"},
+		{"tagged dollar quote", "$fn$ :x $fn$ = :x", namedParams, "$fn$ :x $fn$ = <:x>"},
+		{"identifier with dollar", "col$1 = $1", positionalParams, "col$1 = <$1>"},
+		{"identifier with colon", "a:b = :c", namedParams, "a:b = <:c>"},
+		{"question marks", "tags ?? ? AND a = $1", questionParams, "tags ? <?> AND a = $1"},
+		{"question in literal", "'?' || ?", questionParams, "'?' || <?>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rewriteSQL(tt.query, tt.style, repl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("rewriteSQL(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	boom := errors.New("boom")
+	if _, err := rewriteSQL("a = $1", positionalParams, func(string) (string, error) { return "", boom }); !errors.Is(err, boom) {
+		t.Errorf("rewriteSQL() error = %v, want %v", err, boom)
+	}
+}
+
+func TestCompileNamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		params   P
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  string
+	}{
+		{"single", "email = :email", P{"email": "a@b"}, "email = $1", []interface{}{"a@b"}, ""},
+		{"order of first use", "b = :b AND a = :a", P{"a": 1, "b": 2}, "b = $1 AND a = $2", []interface{}{2, 1}, ""},
+		{"reused", "a = :x OR b = :x", P{"x": 3}, "a = $1 OR b = $1", []interface{}{3}, ""},
+		{"unused params", "a = :x", P{"x": 1, "y": 2}, "a = $1", []interface{}{1}, ""},
+		{"cast not a param", "a = :x::int", P{"x": "1"}, "a = $1::int", []interface{}{"1"}, ""},
+		{"missing", "a = :x", P{}, "", nil, "missing value for named parameter :x"},
+		{"mixed", "a = :x AND b = $1", P{"x": 1}, "", nil, "cannot mix positional $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := compileNamed(tt.query, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("compileNamed() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("compileNamed() = %q %v, want %q %v", sql, args, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestShiftPlaceholders(t *testing.T) {
+	tests := []struct {
+		query string
+		start int
+		want  string
+	}{
+		{"a = $1", 1, "a = $1"},
+		{"a = $1 AND b = $2", 3, "a = $3 AND b = $4"},
+		{"a = $2 OR b = $1", 10, "a = $11 OR b = $10"},
+		{"a = '$1' AND b = $1", 2, "a = '$1' AND b = $2"},
+		{"a = $1 -- $1", 5, "a = $5 -- $1"},
+		{"$$ $1 $$ = $1", 2, "$$ $1 $$ = $2"},
+		{"a::int = $1", 4, "a::int = $4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := shiftPlaceholders(tt.query, tt.start); got != tt.want {
+				t.Errorf("shiftPlaceholders(%q, %d) = %q, want %q", tt.query, tt.start, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/skssmd/norm/core/registry"
+	"github.com/skssmd/norm/core/utils"
+)
+
+// Preload eager-loads related records into fields tagged norm:"rel"
+// The relation is inferred from fkey/skey tags: a field holding one record is
+// belongs-to (this table references the other) or has-one (the other table
+// references this one); a slice field is has-many. Each relation is loaded
+// with one batched IN query routed to the related table's pool or shards.
+// Nested relations use dots: Preload("Orders.Items").
+// Usage:
+//
+//	type User struct {
+//	    ID     uint    `norm:"pk;auto"`
+//	    Orders []Order `norm:"rel"`
+//	}
+//	type Order struct {
+//	    ID     uint  `norm:"pk;auto"`
+//	    UserID uint  `norm:"skey:users.id"`
+//	    User   *User `norm:"rel"`
+//	}
+//
+//	var users []User
+//	err := norm.Table("users").Select().Preload("Orders").All(ctx, &users)
+func (q *Query[T]) Preload(relations ...string) *Query[T] {
+	q.preloads = append(q.preloads, relations...)
+	return q
+}
+
+// withRelations runs the requested preloads after First/All filled dest
+// Partial scatter results are preloaded too; the scatter error is kept.
+func (q *Query[T]) withRelations(ctx context.Context, dest interface{}, err error) error {
+	if len(q.preloads) == 0 || (err != nil && !isPartial(err)) {
+		return err
+	}
+	if perr := q.loadRelations(ctx, dest); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// loadRelations runs the requested preloads against a scanned destination
+func (q *Query[T]) loadRelations(ctx context.Context, dest interface{}) error {
+	if len(q.preloads) == 0 || dest == nil {
+		return nil
+	}
+
+	parents, structType, err := structElems(dest)
+	if err != nil {
+		return fmt.Errorf("preload: %w", err)
+	}
+	return q.preloadTree(ctx, parents, structType, q.preloads)
+}
+
+// preloadTree loads the first segment of every path, then recurses into the loaded records
+func (q *Query[T]) preloadTree(ctx context.Context, parents []reflect.Value, structType reflect.Type, paths []string) error {
+	nested := make(map[string][]string)
+	var order []string
+	for _, path := range paths {
+		name, rest, _ := strings.Cut(path, ".")
+		if _, seen := nested[name]; !seen {
+			order = append(order, name)
+			nested[name] = nil
+		}
+		if rest != "" {
+			nested[name] = append(nested[name], rest)
+		}
+	}
+
+	for _, name := range order {
+		if err := q.preloadRelation(ctx, parents, structType, name, nested[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relation describes how a rel field links two tables
+type relation struct {
+	kind        string // "belongs-to", "has-one" or "has-many"
+	targetTable string
+	localCol    string // column on the parent holding the join value
+	remoteCol   string // column on the related table matched against it
+}
+
+// preloadRelation loads one relation for all parents with a single IN query
+func (q *Query[T]) preloadRelation(ctx context.Context, parents []reflect.Value, structType reflect.Type, name string, nested []string) error {
+	sf, ok := structType.FieldByName(name)
+	if !ok {
+		return fmt.Errorf("preload: %s has no field %s", structType.Name(), name)
+	}
+	if !utils.IsRelationField(sf) {
+		return fmt.Errorf("preload: field %s.%s is not tagged norm:\"rel\"", structType.Name(), name)
+	}
+
+	many := false
+	targetType := sf.Type
+	if targetType.Kind() == reflect.Slice {
+		many = true
+		targetType = targetType.Elem()
+	}
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+	if targetType.Kind() != reflect.Struct {
+		return fmt.Errorf("preload: field %s.%s must be a struct, struct pointer or slice of structs", structType.Name(), name)
+	}
+
+	rel, err := resolveRelation(structType, sf, targetType, many)
+	if err != nil {
+		return err
+	}
+	debugLog("Preload %s.%s (%s) %s.%s IN parent.%s", structType.Name(), name, rel.kind, rel.targetTable, rel.remoteCol, rel.localCol)
+
+	local := fieldByColumn(structType, rel.localCol)
+	if local == nil {
+		return fmt.Errorf("preload: %s has no field for column %s", structType.Name(), rel.localCol)
+	}
+	remote := fieldByColumn(targetType, rel.remoteCol)
+	if remote == nil {
+		return fmt.Errorf("preload: %s has no field for column %s", targetType.Name(), rel.remoteCol)
+	}
+
+	// Collect distinct join values
+	var keys []interface{}
+	seen := make(map[string]bool)
+	for _, p := range parents {
+		v, ok := keyValue(fieldValue(p, local))
+		if !ok {
+			continue
+		}
+		k := fmt.Sprint(v)
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, v)
+		}
+	}
+
+	// Fetch related records
+	children := reflect.MakeSlice(reflect.SliceOf(targetType), 0, 0)
+	for start := 0; start < len(keys); start += maxBindParams {
+		end := min(start+maxBindParams, len(keys))
+
+		sub := &Query[any]{tx: q.tx, allowPartial: q.allowPartial, strict: q.strict}
+		sub.Table(rel.targetTable).Select()
+		sub.Where(In(rel.remoteCol, keys[start:end]))
+
+		part := reflect.New(reflect.SliceOf(targetType))
+		if err := sub.All(ctx, part.Interface()); err != nil {
+			return fmt.Errorf("preload %s: %w", name, err)
+		}
+		children = reflect.AppendSlice(children, part.Elem())
+	}
+
+	// Nested relations are loaded before the records are attached to their parents
+	if len(nested) > 0 && children.Len() > 0 {
+		elems := make([]reflect.Value, children.Len())
+		for i := range elems {
+			elems[i] = children.Index(i)
+		}
+		if err := q.preloadTree(ctx, elems, targetType, nested); err != nil {
+			return err
+		}
+	}
+
+	// Index related records by their join value
+	byKey := make(map[string][]reflect.Value)
+	for i := 0; i < children.Len(); i++ {
+		child := children.Index(i)
+		if v, ok := keyValue(fieldValue(child, remote)); ok {
+			k := fmt.Sprint(v)
+			byKey[k] = append(byKey[k], child)
+		}
+	}
+
+	// Attach
+	for _, p := range parents {
+		field := p.FieldByIndex(sf.Index)
+		v, ok := keyValue(fieldValue(p, local))
+		var matches []reflect.Value
+		if ok {
+			matches = byKey[fmt.Sprint(v)]
+		}
+
+		if many {
+			slice := reflect.MakeSlice(field.Type(), 0, len(matches))
+			for _, m := range matches {
+				slice = reflect.Append(slice, adaptRecord(m, field.Type().Elem()))
+			}
+			field.Set(slice)
+			continue
+		}
+
+		if len(matches) > 0 {
+			field.Set(adaptRecord(matches[0], field.Type()))
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	return nil
+}
+
+// resolveRelation infers a relation from the fkey/skey tags of both tables
+// An optional norm:"rel:column" names the key column when several would match.
+func resolveRelation(parentType reflect.Type, sf reflect.StructField, targetType reflect.Type, many bool) (relation, error) {
+	parentTable := registry.GetRegisteredTableName(reflect.Zero(parentType).Interface())
+	targetTable := registry.GetRegisteredTableName(reflect.Zero(targetType).Interface())
+	if parentTable == "" || targetTable == "" {
+		return relation{}, fmt.Errorf("preload: %s and %s must both be registered tables", parentType.Name(), targetType.Name())
+	}
+	parentTM, _ := registry.GetModel(parentTable)
+	targetTM, _ := registry.GetModel(targetTable)
+
+	hint := ""
+	if v, ok := utils.ParseNormTags(sf.Tag.Get("norm"))["rel"].(string); ok {
+		hint = v
+	}
+
+	// belongs-to: a parent column references the target table
+	if !many {
+		for _, f := range parentTM.Fields {
+			if hint != "" && f.Fieldname != hint {
+				continue
+			}
+			if table, col, ok := keyReference(f); ok && table == targetTable {
+				return relation{kind: "belongs-to", targetTable: targetTable, localCol: f.Fieldname, remoteCol: col}, nil
+			}
+		}
+	}
+
+	// has-one / has-many: a target column references the parent table
+	for _, f := range targetTM.Fields {
+		if hint != "" && f.Fieldname != hint {
+			continue
+		}
+		if table, col, ok := keyReference(f); ok && table == parentTable {
+			kind := "has-one"
+			if many {
+				kind = "has-many"
+			}
+			return relation{kind: kind, targetTable: targetTable, localCol: col, remoteCol: f.Fieldname}, nil
+		}
+	}
+
+	return relation{}, fmt.Errorf("preload: no fkey/skey links %s and %s for field %s", parentTable, targetTable, sf.Name)
+}
+
+// keyReference splits a field's fkey/skey "table.column"
+func keyReference(f registry.Field) (table, column string, ok bool) {
+	ref := f.Fkey
+	if ref == "" {
+		ref = f.Skey
+	}
+	if ref == "" {
+		return "", "", false
+	}
+	return strings.Cut(ref, ".")
+}
+
+// structElems returns the addressable struct values held by dest (*S, *[]S or *[]*S)
+func structElems(dest interface{}) ([]reflect.Value, reflect.Type, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, nil, fmt.Errorf("dest must be a non-nil pointer")
+	}
+	v = v.Elem()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{v}, v.Type(), nil
+	case reflect.Slice:
+		elemType := v.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+		if isPtr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() != reflect.Struct {
+			break
+		}
+		elems := make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			if isPtr {
+				if e.IsNil() {
+					continue
+				}
+				e = e.Elem()
+			}
+			elems = append(elems, e)
+		}
+		return elems, elemType, nil
+	}
+	return nil, nil, fmt.Errorf("dest must be a struct or a slice of structs, got %s", v.Type())
+}
+
+// fieldByColumn finds the struct field mapped to a column (nil when none is)
+func fieldByColumn(t reflect.Type, column string) *utils.FieldPlan {
+	return utils.PlanOf(t).Column(column)
+}
+
+// fieldValue returns the field of v, or an invalid value behind a nil embedded struct
+func fieldValue(v reflect.Value, f *utils.FieldPlan) reflect.Value {
+	fv, _ := f.Get(v)
+	return fv
+}
+
+// keyValue dereferences a join column value; nil pointers have no key
+func keyValue(v reflect.Value) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), true
+}
+
+// adaptRecord converts a loaded struct value to the field's element type (S or *S)
+func adaptRecord(rec reflect.Value, want reflect.Type) reflect.Value {
+	if want.Kind() == reflect.Ptr {
+		p := reflect.New(rec.Type())
+		p.Elem().Set(rec)
+		return p
+	}
+	return rec
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+type preloadAuthor struct {
+	ID      int                   `norm:"pk"`
+	Posts   []preloadPost         `norm:"rel"`
+	Reviews []preloadPost         `norm:"rel:reviewer_id"`
+	Profile *preloadProfile       `norm:"rel"`
+	Notes   []preloadUnregistered `norm:"rel"`
+}
+
+type preloadPost struct {
+	ID         int            `norm:"pk"`
+	AuthorID   int            `norm:"fkey:preload_authors.id"`
+	ReviewerID int            `norm:"skey:preload_authors.id"`
+	Author     *preloadAuthor `norm:"rel"`
+	Reviewer   preloadAuthor  `norm:"rel:reviewer_id"`
+	Profile    preloadProfile `norm:"rel"`
+}
+
+type preloadProfile struct {
+	ID       int `norm:"pk"`
+	AuthorID int `norm:"skey:preload_authors.id"`
+}
+
+type preloadUnregistered struct {
+	ID int `norm:"pk"`
+}
+
+func TestResolveRelation(t *testing.T) {
+	registry.Table(preloadAuthor{}, "preload_authors")
+	registry.Table(preloadPost{}, "preload_posts")
+	registry.Table(preloadProfile{}, "preload_profiles")
+
+	authorType := reflect.TypeOf(preloadAuthor{})
+	postType := reflect.TypeOf(preloadPost{})
+
+	tests := []struct {
+		name    string
+		parent  reflect.Type
+		field   string
+		target  reflect.Type
+		many    bool
+		want    relation
+		wantErr bool
+	}{
+		{
+			name: "has-many", parent: authorType, field: "Posts", target: postType, many: true,
+			want: relation{kind: "has-many", targetTable: "preload_posts", localCol: "id", remoteCol: "author_id"},
+		},
+		{
+			name: "has-many by hint", parent: authorType, field: "Reviews", target: postType, many: true,
+			want: relation{kind: "has-many", targetTable: "preload_posts", localCol: "id", remoteCol: "reviewer_id"},
+		},
+		{
+			name: "has-one", parent: authorType, field: "Profile", target: reflect.TypeOf(preloadProfile{}),
+			want: relation{kind: "has-one", targetTable: "preload_profiles", localCol: "id", remoteCol: "author_id"},
+		},
+		{
+			name: "belongs-to", parent: postType, field: "Author", target: authorType,
+			want: relation{kind: "belongs-to", targetTable: "preload_authors", localCol: "author_id", remoteCol: "id"},
+		},
+		{
+			name: "belongs-to by hint", parent: postType, field: "Reviewer", target: authorType,
+			want: relation{kind: "belongs-to", targetTable: "preload_authors", localCol: "reviewer_id", remoteCol: "id"},
+		},
+		{
+			name: "no key links the tables", parent: postType, field: "Profile", target: reflect.TypeOf(preloadProfile{}),
+			wantErr: true,
+		},
+		{
+			name: "unregistered target", parent: authorType, field: "Notes", target: reflect.TypeOf(preloadUnregistered{}), many: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, ok := tt.parent.FieldByName(tt.field)
+			if !ok {
+				t.Fatalf("%s has no field %s", tt.parent, tt.field)
+			}
+			got, err := resolveRelation(tt.parent, sf, tt.target, tt.many)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRelation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveRelation() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
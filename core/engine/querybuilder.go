@@ -15,7 +15,8 @@ import (
 type JoinDefinition struct {
 	Table string
 	On    string
-	Type  string // "INNER", "LEFT", "RIGHT"
+	Type  string        // "INNER", "LEFT", "RIGHT", "FULL", "CROSS"
+	Args  []interface{} // args for placeholders in Table/On, numbered from $1
 }
 
 // QueryBuilder builds SQL queries with a fluent API
@@ -31,13 +32,26 @@ type QueryBuilder struct {
 	bulkRows        [][]interface{}
 	onConflict      string   // Conflict target columns
 	conflictAction  string   // "nothing" or "update"
-	conflictUpdates []string // Columns to update on conflict
+	conflictUpdates []string // Columns to update on conflict (none = every non-key column)
+
+	conflictConstraint  string        // ON CONFLICT ON CONSTRAINT name (see OnConstraint)
+	conflictTargetWhere string        // partial index predicate of the conflict target
+	conflictTargetArgs  []interface{} // args for conflictTargetWhere, numbered from $1
+	conflictSets        []conflictSet // DO UPDATE SET col = expr (see DoUpdateSet)
+	conflictWhere       string        // DO UPDATE ... WHERE condition
+	conflictWhereArgs   []interface{} // args for conflictWhere, numbered from $1
+
+	groupBy         []string
+	having          string
+	havingArgs      []interface{}
 	orderBy         string
 	limit           int
 	offset          int
 	queryType        string // "select", "update", "delete", "insert", "bulkinsert"
 	joins            []JoinDefinition
+	fromArgs         []interface{} // args for placeholders in a derived FROM source, numbered from $1
 	returningColumns []string
+	err              error // deferred builder error, returned by Build
 }
 
 // From creates a new query builder for the specified model
@@ -218,11 +232,30 @@ type FieldSelector struct {
 	FieldName string
 }
 
-// Where adds a WHERE clause with parameterized values
-// Usage: Where("id = $1 AND status = $2", 1, "active")
-func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuilder {
-	qb.whereClause = condition
-	qb.whereArgs = args
+// Where adds a WHERE condition, ANDed with any previous conditions
+// condition is either SQL with placeholders numbered from $1 or a Cond.
+// Placeholders are renumbered so chained conditions compose.
+// Usage:
+//
+//	Where("id = $1 AND status = $2", 1, "active")
+//	Where("email = :email", P{"email": e})
+//	Where(Eq("status", "active")).Where(In("role", roles))
+func (qb *QueryBuilder) Where(condition interface{}, args ...interface{}) *QueryBuilder {
+	qb.addWhere("AND", false, condition, args)
+	return qb
+}
+
+// OrWhere adds a WHERE condition, ORed with the previous conditions
+// Usage: Where(Eq("role", "admin")).OrWhere(Eq("owner_id", uid))
+func (qb *QueryBuilder) OrWhere(condition interface{}, args ...interface{}) *QueryBuilder {
+	qb.addWhere("OR", false, condition, args)
+	return qb
+}
+
+// WhereNot adds a negated WHERE condition, ANDed with the previous conditions
+// Usage: WhereNot(Like("email", "%@test.com"))
+func (qb *QueryBuilder) WhereNot(condition interface{}, args ...interface{}) *QueryBuilder {
+	qb.addWhere("AND", true, condition, args)
 	return qb
 }
 
@@ -271,61 +304,64 @@ func (qb *QueryBuilder) extractFieldsFromModel(model interface{}) map[string]int
 		return fields
 	}
 
-	t := v.Type()
-
-	for i := 0; i < t.NumField(); i++ {
-		sf := t.Field(i)
-		fv := v.Field(i)
-
-		// skip unexported fields
-		if sf.PkgPath != "" {
+	// unexported fields and relations are not part of the plan
+	for _, f := range utils.PlanOf(v.Type()).Fields {
+		// skip zero values (unset fields), including those of nil embedded structs
+		fv, ok := f.Get(v)
+		if !ok || fv.IsZero() {
 			continue
 		}
 
-		// skip zero values (unset fields)
-		if fv.IsZero() {
-			continue
-		}
-
-		columnName := utils.ResolveColumnName(sf)
-		fields[columnName] = fv.Interface()
+		fields[f.Column] = fv.Interface()
 	}
 
 	return fields
 }
 
-
 // extractAllFieldsFromModel extracts ALL fields from a model instance (including zero values)
-	func (qb *QueryBuilder) extractAllFieldsFromModel(model interface{}) map[string]interface{} {
-		fields := make(map[string]interface{})
-
-		v := reflect.ValueOf(model)
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
-		}
+func (qb *QueryBuilder) extractAllFieldsFromModel(model interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
 
-		if v.Kind() != reflect.Struct {
-			return fields
-		}
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
 
-		t := v.Type()
+	if v.Kind() != reflect.Struct {
+		return fields
+	}
 
-		for i := 0; i < t.NumField(); i++ {
-			sf := t.Field(i)
-			fv := v.Field(i)
+	for _, f := range utils.PlanOf(v.Type()).Fields {
+		if fv, ok := f.Get(v); ok {
+			fields[f.Column] = fv.Interface()
+		} else {
+			fields[f.Column] = nil // nil embedded struct
+		}
+	}
 
-			// skip unexported
-			if sf.PkgPath != "" {
-				continue
-			}
+	return fields
+}
 
-			columnName := utils.ResolveColumnName(sf)
-			fields[columnName] = fv.Interface()
-		}
+// GroupBy adds a GROUP BY clause
+// Usage: Select("status", "COUNT(*) AS n").GroupBy("status")
+func (qb *QueryBuilder) GroupBy(columns ...string) *QueryBuilder {
+	qb.groupBy = append(qb.groupBy, columns...)
+	return qb
+}
 
-		return fields
+// Having adds a HAVING clause
+// Placeholders start at $1 and are renumbered after the WHERE args.
+// Usage: Having("COUNT(*) > $1", 5) or Having("COUNT(*) > :min", P{"min": 5})
+func (qb *QueryBuilder) Having(condition string, args ...interface{}) *QueryBuilder {
+	condition, args, err := bindArgs(condition, args)
+	if err != nil {
+		qb.err = err
+		return qb
 	}
-
+	qb.having = condition
+	qb.havingArgs = args
+	return qb
+}
 
 // OrderBy adds ORDER BY clause
 // Usage: OrderBy("created_at DESC")
@@ -356,20 +392,36 @@ func (qb *QueryBuilder) Pagination(limit, offset int) *QueryBuilder {
 
 // Build generates the SQL query and arguments
 func (qb *QueryBuilder) Build() (string, []interface{}, error) {
+	if qb.err != nil {
+		return "", nil, qb.err
+	}
+	var sql string
+	var args []interface{}
+	var err error
 	switch qb.queryType {
 	case "select":
-		return qb.buildSelect()
+		sql, args, err = qb.buildSelect()
 	case "update":
-		return qb.buildUpdate()
+		sql, args, err = qb.buildUpdate()
 	case "delete":
-		return qb.buildDelete()
+		sql, args, err = qb.buildDelete()
 	case "insert":
-		return qb.buildInsert()
+		sql, args, err = qb.buildInsert()
 	case "bulkinsert":
-		return qb.buildBulkInsert()
+		sql, args, err = qb.buildBulkInsert()
 	default:
 		return "", nil, fmt.Errorf("query type not specified")
 	}
+	if err != nil {
+		return "", nil, err
+	}
+	// Inline Expression values (norm.Expr, Inc, Col, Now) and renumber the rest
+	if sql, args, err = expandExpressions(sql, args); err != nil {
+		return "", nil, err
+	}
+	// Encode values of registered types (see RegisterType)
+	args, err = encodeArgs(args)
+	return sql, args, err
 }
 
 // buildSelect builds a SELECT query
@@ -387,12 +439,29 @@ func (qb *QueryBuilder) buildSelect() (string, []interface{}, error) {
 		sql.WriteString(strings.Join(qb.columns, ", "))
 	}
 
+	// WHERE args come first; derived tables and JOIN args are numbered after them
+	args := qb.whereArgs
+	from := qb.tableName
+	if len(qb.fromArgs) > 0 {
+		from = shiftPlaceholders(from, len(args)+1)
+		args = append(append([]interface{}{}, args...), qb.fromArgs...)
+	}
 	sql.WriteString(" FROM ")
-	sql.WriteString(qb.tableName)
+	sql.WriteString(from)
 
 	// Add JOIN clauses
 	for _, join := range qb.joins {
-		sql.WriteString(fmt.Sprintf(" %s JOIN %s ON %s", join.Type, join.Table, join.On))
+		table, on := join.Table, join.On
+		if len(join.Args) > 0 {
+			table = shiftPlaceholders(table, len(args)+1)
+			on = shiftPlaceholders(on, len(args)+1)
+			args = append(append([]interface{}{}, args...), join.Args...)
+		}
+		if on == "" {
+			sql.WriteString(fmt.Sprintf(" %s JOIN %s", join.Type, table))
+			continue
+		}
+		sql.WriteString(fmt.Sprintf(" %s JOIN %s ON %s", join.Type, table, on))
 	}
 
 	if qb.whereClause != "" {
@@ -400,6 +469,17 @@ func (qb *QueryBuilder) buildSelect() (string, []interface{}, error) {
 		sql.WriteString(qb.whereClause)
 	}
 
+	if len(qb.groupBy) > 0 {
+		sql.WriteString(" GROUP BY ")
+		sql.WriteString(strings.Join(qb.groupBy, ", "))
+	}
+
+	if qb.having != "" {
+		sql.WriteString(" HAVING ")
+		sql.WriteString(shiftPlaceholders(qb.having, len(args)+1))
+		args = append(append([]interface{}{}, args...), qb.havingArgs...)
+	}
+
 	if qb.orderBy != "" {
 		sql.WriteString(" ORDER BY ")
 		sql.WriteString(qb.orderBy)
@@ -413,7 +493,7 @@ func (qb *QueryBuilder) buildSelect() (string, []interface{}, error) {
 		sql.WriteString(fmt.Sprintf(" OFFSET %d", qb.offset))
 	}
 
-	return sql.String(), qb.whereArgs, nil
+	return sql.String(), args, nil
 }
 
 // buildUpdate builds an UPDATE query
@@ -452,11 +532,14 @@ func (qb *QueryBuilder) buildUpdate() (string, []interface{}, error) {
 	if qb.whereClause != "" {
 		sql.WriteString(" WHERE ")
 		// Adjust parameter placeholders in WHERE clause
-		adjustedWhere := qb.adjustPlaceholders(qb.whereClause, paramIndex)
+		adjustedWhere := shiftPlaceholders(qb.whereClause, paramIndex)
 		sql.WriteString(adjustedWhere)
 		args = append(args, qb.whereArgs...)
 	}
 
+	// Add RETURNING clause if specified
+	sql.WriteString(qb.returningClause())
+
 	return sql.String(), args, nil
 }
 
@@ -475,6 +558,9 @@ func (qb *QueryBuilder) buildDelete() (string, []interface{}, error) {
 		sql.WriteString(qb.whereClause)
 	}
 
+	// Add RETURNING clause if specified
+	sql.WriteString(qb.returningClause())
+
 	return sql.String(), qb.whereArgs, nil
 }
 
@@ -488,16 +574,12 @@ func (qb *QueryBuilder) buildInsert() (string, []interface{}, error) {
 	}
 
 	var sql strings.Builder
-	var args []interface{}
-	var columns []string
 	var placeholders []string
 
-	paramIndex := 1
-	for field, value := range qb.insertFields {
-		columns = append(columns, field)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", paramIndex))
-		args = append(args, value)
-		paramIndex++
+	// Columns in sorted order so the statement text is stable
+	columns, args := qb.insertRow()
+	for i := range columns {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
 	}
 
 	sql.WriteString("INSERT INTO ")
@@ -509,32 +591,15 @@ func (qb *QueryBuilder) buildInsert() (string, []interface{}, error) {
 	sql.WriteString(")")
 
 	// Add ON CONFLICT clause if specified
-	if qb.onConflict != "" {
-		sql.WriteString(" ON CONFLICT (")
-		sql.WriteString(qb.onConflict)
-		sql.WriteString(") DO ")
-
-		if qb.conflictAction == "nothing" {
-			sql.WriteString("NOTHING")
-		} else if qb.conflictAction == "update" && len(qb.conflictUpdates) > 0 {
-			sql.WriteString("UPDATE SET ")
-			updates := []string{}
-			for _, col := range qb.conflictUpdates {
-				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-			}
-			sql.WriteString(strings.Join(updates, ", "))
-		}
+	conflict, conflictArgs, err := qb.conflictClause(columns, len(args)+1)
+	if err != nil {
+		return "", nil, err
 	}
+	sql.WriteString(conflict)
+	args = append(args, conflictArgs...)
 
 	// Add RETURNING clause if specified
-	if qb.returningColumns != nil {
-		sql.WriteString(" RETURNING ")
-		if len(qb.returningColumns) == 0 {
-			sql.WriteString("*")
-		} else {
-			sql.WriteString(strings.Join(qb.returningColumns, ", "))
-		}
-	}
+	sql.WriteString(qb.returningClause())
 
 	return sql.String(), args, nil
 }
@@ -576,36 +641,17 @@ func (qb *QueryBuilder) buildBulkInsert() (string, []interface{}, error) {
 	sql.WriteString(strings.Join(valueSets, ", "))
 
 	// Add ON CONFLICT clause if specified
-	if qb.onConflict != "" {
-		sql.WriteString(" ON CONFLICT (")
-		sql.WriteString(qb.onConflict)
-		sql.WriteString(") DO ")
-
-		if qb.conflictAction == "nothing" {
-			sql.WriteString("NOTHING")
-		} else if qb.conflictAction == "update" && len(qb.conflictUpdates) > 0 {
-			sql.WriteString("UPDATE SET ")
-			updates := []string{}
-			for _, col := range qb.conflictUpdates {
-				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-			}
-			sql.WriteString(strings.Join(updates, ", "))
-		}
+	conflict, conflictArgs, err := qb.conflictClause(qb.bulkColumns, paramIndex)
+	if err != nil {
+		return "", nil, err
 	}
+	sql.WriteString(conflict)
+	args = append(args, conflictArgs...)
 
-	return sql.String(), args, nil
-}
+	// Add RETURNING clause if specified
+	sql.WriteString(qb.returningClause())
 
-// adjustPlaceholders adjusts $1, $2, etc. to start from a different index
-func (qb *QueryBuilder) adjustPlaceholders(query string, startIndex int) string {
-	result := query
-	// Replace in reverse order to avoid conflicts (e.g., $1 -> $2 affecting $10)
-	for i := 100; i >= 1; i-- {
-		old := fmt.Sprintf("$%d", i)
-		new := fmt.Sprintf("$%d", startIndex+i-1)
-		result = strings.ReplaceAll(result, old, new)
-	}
-	return result
+	return sql.String(), args, nil
 }
 
 // BulkInsertBuilder handles bulk insert with transaction
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/skssmd/norm/core/driver"
+)
+
+// isWrite reports whether q is a builder UPDATE or DELETE, which First/All run with RETURNING
+func (q *Query[T]) isWrite() bool {
+	if q.builder == nil || q.rawSQL != "" || q.joinContext != nil {
+		return false
+	}
+	return q.builder.queryType == "update" || q.builder.queryType == "delete"
+}
+
+// executeWrite runs an UPDATE/DELETE with RETURNING and scans the changed rows into dest
+// Without Returning every column is returned. Updates and deletes that can't be
+// pinned to one shard of a row-sharded table run on every shard (one after
+// another, like Exec) and their rows are combined. Results are never cached.
+// Usage:
+//
+//	var changed []User
+//	err := norm.Table("users").Update("active", false).Where("last_seen < $1", cutoff).
+//	    Returning("id", "email").All(ctx, &changed)
+func (q *Query[T]) executeWrite(ctx context.Context, dest interface{}, singleRow bool) error {
+	if q.err != nil {
+		return q.err
+	}
+	if q.builder.returningColumns == nil {
+		q.Returning()
+	}
+
+	// Soft keys with ondelete actions are enforced before the rows are deleted,
+	// in one transaction with the delete when every table is on the same pool
+	if q.hasSoftCascade() && q.tx == nil && q.cascadeOnOnePool() {
+		return runTx(ctx, &Tx{}, func(tx *Tx) error {
+			in := *q
+			in.tx = tx
+			return in.executeWrite(ctx, dest, singleRow)
+		})
+	}
+	if q.hasSoftCascade() {
+		if _, err := q.cascadeDependents(ctx); err != nil {
+			return err
+		}
+	}
+	if err := q.checkSoftKeys(ctx); err != nil {
+		return err
+	}
+
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return err
+	}
+
+	shards := []string{""}
+	if tm := q.keyShardedTable(); tm != nil {
+		_, ok, err := q.shardKeyValue(tm)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			shards = tm.Sharding.Shards()
+			sort.Strings(shards)
+			debugLog("Broadcasting %s RETURNING on table=%s to shards %v", q.builder.queryType, tm.TableName, shards)
+		}
+	}
+
+	if dest == nil {
+		var rows []map[string]interface{}
+		for _, shard := range shards {
+			part, err := q.writeShard(ctx, shard, func(ctx context.Context, db querier) (interface{}, error) {
+				result, err := db.Query(ctx, sql, args...)
+				if err != nil {
+					return nil, err
+				}
+				defer result.Close()
+				return scanRowsToMap(result)
+			})
+			if err != nil {
+				return err
+			}
+			rows = append(rows, part.([]map[string]interface{})...)
+		}
+		q.printResults(rows, false)
+		return nil
+	}
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer")
+	}
+	destElem := destValue.Elem()
+	sliceType := destElem.Type()
+	if destElem.Kind() != reflect.Slice {
+		sliceType = reflect.SliceOf(destElem.Type())
+	}
+
+	merged := reflect.MakeSlice(sliceType, 0, 0)
+	for _, shard := range shards {
+		part, err := q.writeShard(ctx, shard, func(ctx context.Context, db querier) (interface{}, error) {
+			result, err := db.Query(ctx, sql, args...)
+			if err != nil {
+				return nil, err
+			}
+			defer result.Close()
+			rows := reflect.New(sliceType)
+			if err := scanRowsToDest(result, rows.Interface(), q.scanOptions()); err != nil {
+				return nil, err
+			}
+			return rows.Elem(), nil
+		})
+		if err != nil {
+			return err
+		}
+		merged = reflect.AppendSlice(merged, part.(reflect.Value))
+	}
+
+	if destElem.Kind() == reflect.Slice {
+		destElem.Set(reflect.AppendSlice(destElem, merged))
+		return nil
+	}
+	if merged.Len() == 0 {
+		return ErrNoRows
+	}
+	destElem.Set(merged.Index(0))
+	return nil
+}
+
+// writeShard runs fn on the connection for shard ("" = the query's routed pool)
+func (q *Query[T]) writeShard(ctx context.Context, shard string, fn func(ctx context.Context, db querier) (interface{}, error)) (interface{}, error) {
+	pool, err := q.getPool()
+	if shard != "" {
+		pool, err = q.poolOnShard(shard)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	value, err := fn(ctx, db)
+	if err != nil {
+		if shard != "" {
+			return nil, fmt.Errorf("query execution failed on shard '%s': %w", shard, err)
+		}
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	return value, nil
+}
+
+// cascadeOnOnePool reports whether q.table and every table its soft-key
+// cascades can reach are written through the same pool, so a single
+// transaction covers the whole delete (row-sharded tables never are)
+func (q *Query[T]) cascadeOnOnePool() bool {
+	var root *driver.PGPool
+	seen := map[string]bool{q.table: true}
+	queue := []string{q.table}
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+
+		pool, ok := writePoolOf(table)
+		if !ok || (root != nil && pool != root) {
+			return false
+		}
+		root = pool
+
+		for _, d := range dependentsOf(table) {
+			if !seen[d.table] {
+				seen[d.table] = true
+				queue = append(queue, d.table)
+			}
+		}
+	}
+	return true
+}
+
+// writePoolOf returns the pool writes to table go to; ok is false for
+// row-sharded tables, whose rows are spread over several pools
+func writePoolOf(table string) (*driver.PGPool, bool) {
+	w := &Query[any]{}
+	w.Table(table)
+	if w.keyShardedTable() != nil {
+		return nil, false
+	}
+	w.builder.queryType = "delete"
+	pool, err := w.getPool()
+	return pool, err == nil
+}
@@ -27,13 +27,27 @@ type Query[T any] struct {
 	cacheTTL    *time.Duration
 	cacheKeys   []string      // Optional cache keys (max 2)
 	rawArgs     []interface{} // Arguments for raw SQL
+	tx          *Tx           // Transaction the query runs in (nil for pool queries)
+
+	allowPartial     bool            // return partial results when some shards of a fan-out fail
+	preloads         []string        // relations to eager-load after First/All (see Preload)
+	validateSoftKeys bool            // check skey values exist before writing (see ValidateSoftKeys)
+	strict           bool            // fail on unmapped columns and unfilled fields when scanning (see Strict)
+	fetchSize        int             // rows per cursor FETCH for Iter/Each (0 = no cursor)
+	targets          []reflect.Value // inserted structs filled from RETURNING rows (see Returning)
+	err              error           // deferred error from building the query (e.g. a missing named parameter)
 }
 
 // JoinContext holds information for join operations
 type JoinContext struct {
-	Tables []string
-	Keys   []string
-	Models []interface{}
+	Tables  []string
+	Keys    []string
+	Models  []interface{}
+	Types   []string                 // join type per table ("INNER", "LEFT", "RIGHT", "FULL", "CROSS"); Types[0] is unused
+	Aliases []string                 // table alias per table ("" when unaliased)
+	Ons     []string                 // explicit ON condition per table (see JoinOn); "" joins on Keys
+	OnArgs  [][]interface{}          // args for each ON condition, numbered from $1
+	Filters map[string]*QueryBuilder // table-scoped WHERE conditions (see WhereTable)
 }
 
 // From creates a new query with routing from model
@@ -57,8 +71,8 @@ func (q *Query[T]) Table(args ...interface{}) *Query[T] {
 		tableNameOrModel := args[0]
 		switch v := tableNameOrModel.(type) {
 		case string:
-			// String-based: just set table name
-			q.table = v
+			// String-based: just set table name ("users u" sets an alias for joins)
+			q.table, _ = splitAlias(v)
 			q.builder = &QueryBuilder{
 				tableName:    v,
 				columns:      []string{},
@@ -104,13 +118,13 @@ func (q *Query[T]) Table(args ...interface{}) *Query[T] {
 		tableArg := args[i]
 		keyArg := args[i+1]
 
-		var tableName string
+		var tableName, alias string
 		var model interface{}
 
 		// Handle Table argument
 		switch v := tableArg.(type) {
 		case string:
-			tableName = v
+			tableName, alias = splitAlias(v)
 		default:
 			tableName = getTableNameFromModel(v)
 			model = v
@@ -139,6 +153,8 @@ func (q *Query[T]) Table(args ...interface{}) *Query[T] {
 		q.joinContext.Tables = append(q.joinContext.Tables, tableName)
 		q.joinContext.Keys = append(q.joinContext.Keys, keyName)
 		q.joinContext.Models = append(q.joinContext.Models, model)
+		q.joinContext.Types = append(q.joinContext.Types, "INNER")
+		q.joinContext.Aliases = append(q.joinContext.Aliases, alias)
 	}
 
 	// Initialize builder with the first table
@@ -148,7 +164,7 @@ func (q *Query[T]) Table(args ...interface{}) *Query[T] {
 
 		q.table = firstTable
 		q.builder = &QueryBuilder{
-			tableName:    firstTable,
+			tableName:    q.joinContext.source(0),
 			model:        firstModel,
 			columns:      []string{},
 			whereArgs:    []interface{}{},
@@ -167,16 +183,30 @@ func (q *Query[T]) Select(fields ...interface{}) *Query[T] {
 	return q
 }
 
-// Where adds WHERE clause
-func (q *Query[T]) Where(condition string, args ...interface{}) *Query[T] {
+// Where adds a WHERE condition (SQL string or Cond), ANDed with previous conditions
+func (q *Query[T]) Where(condition interface{}, args ...interface{}) *Query[T] {
 	q.builder.Where(condition, args...)
 	return q
 }
 
+// OrWhere adds a WHERE condition, ORed with previous conditions
+func (q *Query[T]) OrWhere(condition interface{}, args ...interface{}) *Query[T] {
+	q.builder.OrWhere(condition, args...)
+	return q
+}
+
+// WhereNot adds a negated WHERE condition, ANDed with previous conditions
+func (q *Query[T]) WhereNot(condition interface{}, args ...interface{}) *Query[T] {
+	q.builder.WhereNot(condition, args...)
+	return q
+}
+
 // Update sets fields to update
 // Can be used in two ways:
 // 1. Pair-based: Update("name", "John", "age", 30)
 // 2. Struct-based: Table(User{Name: "John"}).Update().Where(...)
+// Values may be expressions, and norm.Inc can stand in for a whole pair:
+// Update(norm.Inc("views", 1), "seen_at", norm.Now())
 func (q *Query[T]) Update(args ...interface{}) *Query[T] {
 	if len(args) == 0 {
 		// Struct-based update from Table()
@@ -191,8 +221,13 @@ func (q *Query[T]) Update(args ...interface{}) *Query[T] {
 	q.builder.queryType = "update"
 	q.builder.updateFields = make(map[string]interface{})
 
-	for i := 0; i < len(args)-1; i += 2 {
-		if key, ok := args[i].(string); ok {
+	for i := 0; i < len(args); i += 2 {
+		if e, ok := args[i].(Expression); ok && e.column != "" {
+			q.builder.updateFields[e.column] = e
+			i--
+			continue
+		}
+		if key, ok := args[i].(string); ok && i+1 < len(args) {
 			q.builder.updateFields[key] = args[i+1]
 		}
 	}
@@ -211,13 +246,29 @@ func (q *Query[T]) Delete() *Query[T] {
 func (q *Query[T]) Insert(model ...interface{}) *Query[T] {
 	if len(model) > 0 {
 		q.builder.Insert(model[0])
+		q.fillInsertIDs(model[0])
+		q.targets = returnTargets(model[0], false)
 	} else if q.builder.model != nil {
 		// Use model from Table() and extract non-zero fields
 		q.builder.InsertNonZero(q.builder.model)
+		q.fillInsertIDs(q.builder.model)
+		q.targets = returnTargets(q.builder.model, false)
 	}
 	return q
 }
 
+// GroupBy adds GROUP BY clause
+func (q *Query[T]) GroupBy(columns ...string) *Query[T] {
+	q.builder.GroupBy(columns...)
+	return q
+}
+
+// Having adds HAVING clause (placeholders start at $1)
+func (q *Query[T]) Having(condition string, args ...interface{}) *Query[T] {
+	q.builder.Having(condition, args...)
+	return q
+}
+
 // OrderBy adds ORDER BY clause
 func (q *Query[T]) OrderBy(order string) *Query[T] {
 	q.builder.OrderBy(order)
@@ -259,12 +310,17 @@ func (q *Query[T]) BulkInsert(args ...interface{}) *Query[T] {
 		if rows, ok := args[1].([][]interface{}); ok {
 			q.builder.bulkColumns = columns
 			q.builder.bulkRows = rows
+			q.fillBulkIDs(reflect.Value{})
 		}
 		return q
 	}
 
 	// Struct-based mode: extract from slice of structs
 	q.extractBulkFromModels(args[0])
+	if models := reflect.ValueOf(args[0]); models.Kind() == reflect.Slice {
+		q.fillBulkIDs(models)
+		q.targets = returnTargets(args[0], true)
+	}
 	return q
 }
 
@@ -323,7 +379,18 @@ func (q *Query[T]) OnConflict(conflictColumn string, action string, updateColumn
 // Raw sets a raw SQL query for execution
 // Uses the table name (if set) for automatic routing
 // Example: norm.Table("users").Raw("SELECT * FROM users WHERE age > $1", 25)
+// Named parameters: Raw("SELECT * FROM users WHERE email = :email", P{"email": e})
 func (q *Query[T]) Raw(query string, args ...interface{}) *Query[T] {
+	if sql, bound, err := bindArgs(query, args); err != nil {
+		q.err = err
+	} else {
+		query, args = sql, bound
+	}
+	if encoded, err := encodeArgs(args); err != nil {
+		q.err = err
+	} else {
+		args = encoded
+	}
 	q.rawSQL = query
 	q.rawArgs = args
 	
@@ -407,8 +474,10 @@ func (q *Query[T]) generateCacheKey(query string, args []interface{}) string {
 }
 
 // checkCache checks if the query result is cached
+// Queries bound to a transaction bypass the cache: they must see the
+// transaction's own writes.
 func (q *Query[T]) checkCache(ctx context.Context, query string, args []interface{}) ([]byte, bool, error) {
-	if q.cacheTTL == nil {
+	if q.cacheTTL == nil || q.tx != nil {
 		return nil, false, nil
 	}
 
@@ -431,8 +500,10 @@ func (q *Query[T]) checkCache(ctx context.Context, query string, args []interfac
 }
 
 // setCache stores the query result in cache
+// Results read inside a transaction are never cached: they may hold rows
+// other callers can't see yet, or that are rolled back.
 func (q *Query[T]) setCache(ctx context.Context, query string, args []interface{}, data interface{}) error {
-	if q.cacheTTL == nil {
+	if q.cacheTTL == nil || q.tx != nil {
 		return nil
 	}
 
@@ -452,6 +523,25 @@ func (q *Query[T]) setCache(ctx context.Context, query string, args []interface{
 	return cacher.Set(ctx, key, jsonData, *q.cacheTTL)
 }
 
+// conn returns what the query should run on: the pool itself, or the
+// transaction bound to it when the query was built from a Tx
+func (q *Query[T]) conn(ctx context.Context, pool *driver.PGPool) (querier, error) {
+	if q.tx == nil {
+		return pool.Pool, nil
+	}
+	return q.tx.acquire(ctx, pool, q.table)
+}
+
+// routingType returns the query type used for pool selection
+// Queries inside a transaction always route as writes so reads see the
+// transaction's own changes on the writable pool.
+func (q *Query[T]) routingType() string {
+	if q.tx != nil {
+		return "update"
+	}
+	return q.builder.queryType
+}
+
 // getPool determines which pool to use based on query type and table
 
 func (q *Query[T]) getPool() (*driver.PGPool, error) {
@@ -470,9 +560,11 @@ func (q *Query[T]) getPool() (*driver.PGPool, error) {
 }
 
 // getGlobalPool gets pool for global mode
+// Replica and read pools are numbered (replica1, replica2, read1, ...) and
+// balanced with the registry's Balancer.
 func (q *Query[T]) getGlobalPool(info map[string]interface{}) (*driver.PGPool, error) {
 	poolsRaw := info["pools"].(map[string]interface{})
-	queryType := q.builder.queryType
+	queryType := q.routingType()
 
 	// Convert interface{} map to typed map
 	pools := make(map[string]*driver.PGPool)
@@ -483,57 +575,69 @@ func (q *Query[T]) getGlobalPool(info map[string]interface{}) (*driver.PGPool, e
 	}
 
 	// Detect scenario based on available pools
-	hasReadWrite := pools["read"] != nil || pools["write"] != nil
-	hasPrimaryReplica := pools["primary"] != nil && pools["replica"] != nil
+	hasReadWrite := len(registry.PoolGroup("read")) > 0 || pools["write"] != nil
+	hasPrimaryReplica := pools["primary"] != nil && len(registry.PoolGroup("replica")) > 0
+
+	// up returns the named pool if it is registered and healthy
+	up := func(name string) *driver.PGPool {
+		if pool, ok := pools[name]; ok && registry.IsHealthy(pool) {
+			return pool
+		}
+		return nil
+	}
+
+	isRead := queryType == "select"
 
 	if hasPrimaryReplica && !hasReadWrite {
 		// Scenario: Global Primary/Replica
-		// Primary for all writes, fallback to replica when primary down
-		switch queryType {
-		case "select":
-			// Try primary first, fallback to replica when primary down
-			if pool, ok := pools["primary"]; ok {
-				return pool, nil
-			}
-			if pool, ok := pools["replica"]; ok {
+		// Reads are balanced across healthy replicas, falling back to primary.
+		// Writes always go to primary.
+		if isRead {
+			if pool := registry.PickPool("replica"); pool != nil {
 				return pool, nil
 			}
-		case "insert", "update", "delete", "bulkinsert":
-			// Always use primary for writes
-			if pool, ok := pools["primary"]; ok {
+			if pool := up("primary"); pool != nil {
 				return pool, nil
 			}
+			return nil, fmt.Errorf("%w: primary and all replicas are down", registry.ErrNoReadablePool)
+		}
+		if pool := up("primary"); pool != nil {
+			return pool, nil
 		}
+		return nil, fmt.Errorf("%w: primary is down", registry.ErrNoWritablePool)
 	} else if hasReadWrite {
 		// Scenario: Global Read/Write Split
-		// Use role-based routing, fallback read to write when down
-		switch queryType {
-		case "select":
-			// Try read pool first, fallback to write when read down
-			if pool, ok := pools["read"]; ok {
+		// Reads are balanced across healthy read pools, falling back to write, then primary
+		if isRead {
+			if pool := registry.PickPool("read"); pool != nil {
 				return pool, nil
 			}
-			if pool, ok := pools["write"]; ok {
+			if pool := up("write"); pool != nil {
 				return pool, nil
 			}
 			// Last resort: primary
-			if pool, ok := pools["primary"]; ok {
-				return pool, nil
-			}
-		case "insert", "update", "delete", "bulkinsert":
-			// Try write pool first, fallback to primary
-			if pool, ok := pools["write"]; ok {
-				return pool, nil
-			}
-			if pool, ok := pools["primary"]; ok {
+			if pool := up("primary"); pool != nil {
 				return pool, nil
 			}
+			return nil, fmt.Errorf("%w: all read and write pools are down", registry.ErrNoReadablePool)
 		}
-	} else {
+		// Try write pool first, fallback to primary
+		if pool := up("write"); pool != nil {
+			return pool, nil
+		}
+		if pool := up("primary"); pool != nil {
+			return pool, nil
+		}
+		return nil, fmt.Errorf("%w: write pool is down", registry.ErrNoWritablePool)
+	} else if pools["primary"] != nil {
 		// Single pool scenario
-		if pool, ok := pools["primary"]; ok {
+		if pool := up("primary"); pool != nil {
 			return pool, nil
 		}
+		if isRead {
+			return nil, fmt.Errorf("%w: primary is down", registry.ErrNoReadablePool)
+		}
+		return nil, fmt.Errorf("%w: primary is down", registry.ErrNoWritablePool)
 	}
 
 	return nil, fmt.Errorf("no suitable pool found for query type: %s", queryType)
@@ -547,25 +651,48 @@ func (q *Query[T]) getShardPool(info map[string]interface{}) (*driver.PGPool, er
 		return nil, fmt.Errorf("table '%s' not registered", q.table)
 	}
 
-	// Find the shard for the table for any role
-	var shardName, role string
-	found := false
-	for r, shards := range tableModel.Roles {
-		for s := range shards {
-			shardName = s
-			role = r
-			found = true
-			break
-		}
-		if found {
-			break
+	// Key-sharded tables: route by the shard key value
+	if tableModel.IsKeySharded() {
+		shardName, err := q.shardForKey(tableModel)
+		if err != nil {
+			return nil, err
 		}
+		return q.getTablePoolOnShard(info, shardName, "primary")
 	}
 
+	shardName, role, found := pickTableShard(tableModel, q.routingType() == "select")
 	if !found {
 		return nil, fmt.Errorf("no shard found for table '%s'", q.table)
 	}
 
+	return q.getTablePoolOnShard(info, shardName, role)
+}
+
+// pickTableShard deterministically picks the shard serving a table
+// Reads prefer the "read" role and writes the "write" role, then primary and standalone;
+// within a role the first shard by name wins.
+func pickTableShard(tm *registry.TableModel, isRead bool) (shard, role string, found bool) {
+	roles := []string{"write", "primary", "standalone", "read"}
+	if isRead {
+		roles = []string{"read", "primary", "standalone", "write"}
+	}
+
+	for _, r := range roles {
+		if len(tm.Roles[r]) == 0 {
+			continue
+		}
+		shards := make([]string, 0, len(tm.Roles[r]))
+		for s := range tm.Roles[r] {
+			shards = append(shards, s)
+		}
+		sort.Strings(shards)
+		return shards[0], r, true
+	}
+	return "", "", false
+}
+
+// getTablePoolOnShard gets the pool serving q.table on a specific shard
+func (q *Query[T]) getTablePoolOnShard(info map[string]interface{}, shardName, role string) (*driver.PGPool, error) {
 	// Lookup shard info in registry
 	shards := info["shards"].(map[string]interface{})
 	shardInfoRaw, ok := shards[shardName]
@@ -586,30 +713,33 @@ func (q *Query[T]) getShardPool(info map[string]interface{}) (*driver.PGPool, er
 	if spRaw, ok := shardInfo["standalone_pools"]; ok && spRaw != nil {
 		if spMap, ok := spRaw.(map[string]*driver.PGPool); ok {
 			// DEBUG: Print available standalone pools
-				debugLog("Looking for table '%s' in standalone pools of shard '%s'. Available: %v", q.table, shardName, spMap)
+			debugLog("Looking for table '%s' in standalone pools of shard '%s'. Available: %v", q.table, shardName, spMap)
 			if pool, ok := spMap[q.table]; ok {
 				standalonePool = pool
 			}
 		}
 	}
 
-	queryType := q.builder.queryType
+	queryType := q.routingType()
 	debugLog("getShardPool table=%s shard=%s role=%s queryType=%s hasStandalone=%v", q.table, shardName, role, queryType, standalonePool != nil)
-	switch queryType {
-	case "insert", "update", "delete", "bulkinsert":
-		if role == "standalone" && standalonePool != nil {
-			return standalonePool, nil
-		}
-		if primaryPool != nil {
-			return primaryPool, nil
-		}
-	case "select":
-		if role == "standalone" && standalonePool != nil {
-			return standalonePool, nil
-		}
-		if primaryPool != nil {
-			return primaryPool, nil
+	var pool *driver.PGPool
+	if role == "standalone" && standalonePool != nil {
+		pool = standalonePool
+	} else if primaryPool != nil {
+		pool = primaryPool
+	} else if standalonePool != nil {
+		pool = standalonePool
+	}
+
+	if pool != nil {
+		// A shard has a single pool per table: no failover target when it's down
+		if !registry.IsHealthy(pool) {
+			if queryType == "select" {
+				return nil, fmt.Errorf("%w: shard '%s' is down", registry.ErrNoReadablePool, shardName)
+			}
+			return nil, fmt.Errorf("%w: shard '%s' is down", registry.ErrNoWritablePool, shardName)
 		}
+		return pool, nil
 	}
 
 	return nil, fmt.Errorf("no suitable pool found for table '%s' in shard '%s'", q.table, shardName)
@@ -624,11 +754,48 @@ func (q *Query[T]) Exec(ctx ...context.Context) (int64, error) {
 		execCtx = ctx[0]
 	}
 
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	// Soft keys with ondelete actions are enforced by the engine
+	if q.hasSoftCascade() {
+		report, err := q.ExecCascade(execCtx)
+		if report == nil {
+			return 0, err
+		}
+		return report.Rows(q.table), err
+	}
+
+	if err := q.checkSoftKeys(execCtx); err != nil {
+		return 0, err
+	}
+
+	// Key-sharded tables: split bulk inserts per shard, broadcast unpinned writes
+	if tm := q.keyShardedTable(); tm != nil {
+		switch q.builder.queryType {
+		case "bulkinsert":
+			return q.execShardedBulk(execCtx, tm)
+		case "update", "delete":
+			_, ok, err := q.shardKeyValue(tm)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return q.execOnAllShards(execCtx, tm)
+			}
+		}
+	}
+
 	pool, err := q.getPool()
 	if err != nil {
 		return 0, err
 	}
 
+	if q.builder.queryType == "bulkinsert" {
+		return q.execBulk(execCtx, pool, q.builder.bulkRows, q.targets)
+	}
+
 	sql, args, err := q.builder.Build()
 	if err != nil {
 		return 0, err
@@ -637,7 +804,18 @@ func (q *Query[T]) Exec(ctx ...context.Context) (int64, error) {
 	// Debug: print SQL and args (uncomment for debugging)
 	// fmt.Printf("DEBUG SQL: %s\nDEBUG ARGS: %v\n", sql, args)
 
-	result, err := pool.Pool.Exec(execCtx, sql, args...)
+	db, err := q.conn(execCtx, pool)
+	if err != nil {
+		return 0, err
+	}
+
+	// Insert ... RETURNING: scan the row back into the inserted struct
+	if q.builder.queryType == "insert" && q.builder.returningColumns != nil {
+		columns, values := q.builder.insertRow()
+		return q.queryReturning(execCtx, db, sql, args, columns, [][]interface{}{values}, q.targets)
+	}
+
+	result, err := db.Exec(execCtx, sql, args...)
 	if err != nil {
 		return 0, fmt.Errorf("query execution failed: %w", err)
 	}
@@ -652,8 +830,15 @@ func (q *Query[T]) Return(cols ...string) (T, error) {
 	// Use provided context if any, else Background
 	execCtx := context.Background()
 
+	if q.err != nil {
+		return q.model, q.err
+	}
+	if err := q.checkSoftKeys(execCtx); err != nil {
+		return q.model, err
+	}
+
 	// 1. Set returning columns in builder
-	q.builder.Returning(cols...)
+	q.Returning(cols...)
 
 	// 2. Build query
 	sql, args, err := q.builder.Build()
@@ -672,7 +857,12 @@ func (q *Query[T]) Return(cols ...string) (T, error) {
 
 // executeWithReturn handles the actual execution and scanning for Return()
 func (q *Query[T]) executeWithReturn(ctx context.Context, sql string, args []interface{}, pool *driver.PGPool) (T, error) {
-	rows, err := pool.Pool.Query(ctx, sql, args...)
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return q.model, err
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
 	if err != nil {
 		return q.model, fmt.Errorf("insert with return failed: %w", err)
 	}
@@ -682,12 +872,12 @@ func (q *Query[T]) executeWithReturn(ctx context.Context, sql string, args []int
 	modelValue := reflect.ValueOf(q.model)
 	if modelValue.Kind() == reflect.Ptr {
 		// T is already a pointer (e.g., *User), scan directly into it
-		if err := scanRowsToDest(rows, q.model); err != nil {
+		if err := scanRowsToDest(rows, q.model, q.scanOptions()); err != nil {
 			return q.model, err
 		}
 	} else {
 		// T is a value type, scan into &q.model
-		if err := scanRowsToDest(rows, &q.model); err != nil {
+		if err := scanRowsToDest(rows, &q.model, q.scanOptions()); err != nil {
 			return q.model, err
 		}
 	}
@@ -697,6 +887,13 @@ func (q *Query[T]) executeWithReturn(ctx context.Context, sql string, args []int
 
 // First executes query and returns first row
 func (q *Query[T]) First(ctx context.Context, dest interface{}) error {
+	return q.withRelations(ctx, dest, q.first(ctx, dest))
+}
+
+func (q *Query[T]) first(ctx context.Context, dest interface{}) error {
+	if q.isWrite() {
+		return q.executeWrite(ctx, dest, true)
+	}
 	if q.rawSQL != "" {
 		return q.executeRaw(ctx, dest, true)
 	}
@@ -707,37 +904,50 @@ func (q *Query[T]) First(ctx context.Context, dest interface{}) error {
 }
 
 // All executes query and returns all rows
+// dest points to a slice of structs, scalars or map[string]interface{}, or is a ScanMap.
 func (q *Query[T]) All(ctx context.Context, dest interface{}) error {
-	// Optimization: Check cache explicitly BEFORE building query
-	// This works if explicit cache keys are provided via .Cache()
-	if len(q.cacheKeys) > 0 && q.cacheTTL != nil {
-		// Pass empty query/args as they are ignored by generateCacheKey when keys are present
-		if data, hit, _ := q.checkCache(ctx, "", nil); hit {
-			if dest != nil {
-				return json.Unmarshal(data, dest)
-			}
-			// For inspecting results if dest is nil
-			var results []map[string]interface{}
-			if err := json.Unmarshal(data, &results); err != nil {
-				return fmt.Errorf("failed to unmarshal cached data: %w", err)
-			}
-			q.printResults(results, true)
-			return nil
+	// Keyed maps are filled from the rows as maps, whichever way the query runs
+	if k, ok := dest.(*KeyedMap); ok {
+		var rows []map[string]interface{}
+		err := q.all(ctx, &rows)
+		if err != nil && !isPartial(err) {
+			return err
+		}
+		if ferr := k.fill(rows, q.scanOptions()); ferr != nil {
+			return ferr
 		}
+		return err
 	}
+	return q.withRelations(ctx, dest, q.all(ctx, dest))
+}
 
-	if q.rawSQL != "" {
-		return q.executeRaw(ctx, dest, false)
-	}
-	if q.joinContext != nil {
-		return q.executeJoin(ctx, dest, false)
+// Pluck selects a single column and scans it into dest (a pointer to a slice of scalars)
+// On an Update or Delete the column is returned with RETURNING.
+// Usage:
+//
+//	var emails []string
+//	err := norm.Table("users").Where("active = $1", true).Pluck(ctx, "email", &emails)
+func (q *Query[T]) Pluck(ctx context.Context, column string, dest interface{}) error {
+	if q.builder == nil || q.rawSQL != "" {
+		return fmt.Errorf("Pluck needs a table query")
+	}
+	p := *q
+	b := *q.builder
+	if p.isWrite() {
+		b.returningColumns = []string{column}
+	} else {
+		b.queryType = "select"
+		b.columns = []string{column}
 	}
-	return q.executeStandard(ctx, dest, false)
+	p.builder = &b
+	return p.all(ctx, dest)
 }
 
+func (q *Query[T]) all(ctx context.Context, dest interface{}) error {
+	if q.isWrite() {
+		return q.executeWrite(ctx, dest, false)
+	}
 
-// All executes query and returns all rows
-func (q *Query[T]) Batch(ctx context.Context, dest interface{}) error {
 	// Optimization: Check cache explicitly BEFORE building query
 	// This works if explicit cache keys are provided via .Cache()
 	if len(q.cacheKeys) > 0 && q.cacheTTL != nil {
@@ -766,8 +976,8 @@ func (q *Query[T]) Batch(ctx context.Context, dest interface{}) error {
 }
 
 
-// executeRaw executes a raw SQL query with proper routing
-func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow bool) error {
+// rawPool resolves the pool a raw SQL query runs on
+func (q *Query[T]) rawPool() (*driver.PGPool, error) {
 	var pool *driver.PGPool
 	var err error
 
@@ -776,12 +986,12 @@ func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow b
 		// Explicit shard routing
 		pool, err = q.getPoolForShard(q.rawShard)
 		if err != nil {
-			return fmt.Errorf("failed to get pool for shard '%s': %w", q.rawShard, err)
+			return nil, fmt.Errorf("failed to get pool for shard '%s': %w", q.rawShard, err)
 		}
 	} else if q.joinContext != nil {
 		// Join-based routing: validate co-location
 		if len(q.joinContext.Tables) < 2 {
-			return fmt.Errorf("join requires at least 2 tables")
+			return nil, fmt.Errorf("join requires at least 2 tables")
 		}
 		
 		// Check if tables are co-located
@@ -789,12 +999,12 @@ func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow b
 		pool2, err2 := q.getPoolForTable(q.joinContext.Tables[1])
 		
 		if err1 != nil || err2 != nil {
-			return fmt.Errorf("failed to resolve pools for join tables: %v, %v", err1, err2)
+			return nil, fmt.Errorf("failed to resolve pools for join tables: %v, %v", err1, err2)
 		}
 		
 		// Compare pool addresses to check co-location
 		if pool1 != pool2 {
-			return fmt.Errorf("tables '%s' and '%s' are not co-located (different shards/pools). Raw SQL joins only work for co-located tables", 
+			return nil, fmt.Errorf("tables '%s' and '%s' are not co-located (different shards/pools). Raw SQL joins only work for co-located tables", 
 				q.joinContext.Tables[0], q.joinContext.Tables[1])
 		}
 		
@@ -803,10 +1013,27 @@ func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow b
 		// Table-based routing (automatic)
 		pool, err = q.getPool()
 		if err != nil {
-			return fmt.Errorf("failed to get pool for table '%s': %w", q.table, err)
+			return nil, fmt.Errorf("failed to get pool for table '%s': %w", q.table, err)
 		}
+	} else if q.tx != nil && q.tx.boundPool() != nil {
+		// Transaction-based routing: run on the pool the transaction is bound to
+		pool = q.tx.boundPool()
 	} else {
-		return fmt.Errorf("raw SQL requires either a table name, explicit shard, or join context for routing")
+		return nil, fmt.Errorf("raw SQL requires either a table name, explicit shard, or join context for routing")
+	}
+
+	return pool, nil
+}
+
+// executeRaw executes a raw SQL query with proper routing
+func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow bool) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	pool, err := q.rawPool()
+	if err != nil {
+		return err
 	}
 
 	// Check cache
@@ -825,7 +1052,12 @@ func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow b
 	}
 
 	// Execute the raw query
-	rows, err := pool.Pool.Query(ctx, q.rawSQL, q.rawArgs...)
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(ctx, q.rawSQL, q.rawArgs...)
 	if err != nil {
 		return fmt.Errorf("raw query execution failed: %w", err)
 	}
@@ -834,7 +1066,7 @@ func (q *Query[T]) executeRaw(ctx context.Context, dest interface{}, singleRow b
 	// Scan results
 	if dest != nil {
 		// scanRowsToDest handles both single struct and slice cases
-		if err := scanRowsToDest(rows, dest); err != nil {
+		if err := scanRowsToDest(rows, dest, q.scanOptions()); err != nil {
 			return err
 		}
 		// Set cache
@@ -908,6 +1140,15 @@ func (q *Query[T]) getPoolForTable(tableName string) (*driver.PGPool, error) {
 
 // executeStandard executes a standard single-table query
 func (q *Query[T]) executeStandard(ctx context.Context, dest interface{}, singleRow bool) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	// Tables spread over several shards: fan out and merge
+	if shards := q.scatterShards(); len(shards) > 0 {
+		return q.executeScatter(ctx, dest, singleRow, shards)
+	}
+
 	pool, err := q.getPool()
 	if err != nil {
 		return err
@@ -942,14 +1183,19 @@ func (q *Query[T]) executeStandard(ctx context.Context, dest interface{}, single
 	}
 
 	// Execute query
-	rows, err := pool.Pool.Query(ctx, sql, args...)
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("query execution failed: %w", err)
 	}
 	defer rows.Close()
 
 	if dest != nil {
-		if err := scanRowsToDest(rows, dest); err != nil {
+		if err := scanRowsToDest(rows, dest, q.scanOptions()); err != nil {
 			return err
 		}
 		// Set cache
@@ -1036,9 +1282,16 @@ func scanRowsToMap(rows pgx.Rows) ([]map[string]interface{}, error) {
 
 // executeJoin executes a join query (either native or app-side)
 func (q *Query[T]) executeJoin(ctx context.Context, dest interface{}, singleRow bool) error {
+	if q.err != nil {
+		return q.err
+	}
 	if len(q.joinContext.Tables) < 2 {
 		return fmt.Errorf("join requires at least 2 tables")
 	}
+	steps, err := q.joinContext.steps()
+	if err != nil {
+		return err
+	}
 
 	// 1. Check co-location
 	coLocated, err := q.isCoLocated()
@@ -1048,28 +1301,40 @@ func (q *Query[T]) executeJoin(ctx context.Context, dest interface{}, singleRow
 
 	// 2. Decide Native vs App-Side
 	if coLocated {
-		// Configure Native Join
-		for i := 1; i < len(q.joinContext.Tables); i++ {
-			t1 := q.joinContext.Tables[i-1]
-			k1 := q.joinContext.Keys[i-1]
-			t2 := q.joinContext.Tables[i]
-			k2 := q.joinContext.Keys[i]
-
-			onClause := fmt.Sprintf("%s.%s = %s.%s", t1, k1, t2, k2)
-			
-			q.builder.joins = append(q.builder.joins, JoinDefinition{
-				Table: t2,
-				On:    onClause,
-				Type:  "INNER",
-			})
+		// Run the chain as SQL JOINs on a copy, so the query can be executed again
+		b, err := q.nativeJoinBuilder(steps)
+		if err != nil {
+			return err
 		}
-		return q.executeStandard(ctx, dest, singleRow)
+		// Nested destinations (struct{ User User; Order Order }) need each column's table
+		if dest != nil && hasNested(dest) {
+			b.columns = q.joinContext.qualifiedColumns(b.columns)
+		}
+		native := *q
+		native.builder = b
+		return native.executeStandard(ctx, dest, singleRow)
 	}
 
 	// 3. App-Side Join
 	return q.executeAppSideJoin(ctx, dest, singleRow)
 }
 
+// tableShards returns the sorted set of shards a table lives on, over all roles
+func tableShards(tm *registry.TableModel) []string {
+	seen := make(map[string]bool)
+	var shards []string
+	for _, roleShards := range tm.Roles {
+		for s := range roleShards {
+			if !seen[s] {
+				seen[s] = true
+				shards = append(shards, s)
+			}
+		}
+	}
+	sort.Strings(shards)
+	return shards
+}
+
 // isCoLocated checks if all tables in the join context are on the same database/shard
 func (q *Query[T]) isCoLocated() (bool, error) {
 	info := registry.GetRegistryInfo()
@@ -1083,341 +1348,49 @@ func (q *Query[T]) isCoLocated() (bool, error) {
 		return false, fmt.Errorf("unknown registry mode: %s", mode)
 	}
 
-	// Check shards for each table
+	// Native joins need every table on the same single shard
 	var firstShard string
-	
 	for i, tableName := range q.joinContext.Tables {
 		tableModel, exists := registry.GetModel(tableName)
 		if !exists {
 			return false, fmt.Errorf("table '%s' not registered", tableName)
 		}
 
-		// Find shard for this table
-		var shardName string
-		found := false
-		for _, shards := range tableModel.Roles {
-			for s := range shards {
-				shardName = s
-				found = true
-				break
-			}
-			if found {
-				break
-			}
-		}
-
-		if !found {
+		shards := tableShards(tableModel)
+		if len(shards) == 0 {
 			return false, fmt.Errorf("no shard found for table '%s'", tableName)
 		}
-
-		if i == 0 {
-			firstShard = shardName
-		} else {
-			if shardName != firstShard {
-				return false, nil // Different shards
-			}
+		if len(shards) > 1 {
+			return false, nil // Spread across shards
 		}
-	}
 
-	// Check for Skeys
-	for i, tableName := range q.joinContext.Tables {
-		key := q.joinContext.Keys[i]
-		tableModel, _ := registry.GetModel(tableName)
-		if tableModel != nil {
-			for _, field := range tableModel.Fields {
-				if field.Fieldname == key && field.Skey != "" {
-					return false, nil // Force App-Side
-				}
-			}
-		}
-	}
-
-	return true, nil
-}
-
-// executeAppSideJoin executes a join by fetching data from multiple sources and merging
-func (q *Query[T]) executeAppSideJoin(ctx context.Context, dest interface{}, singleRow bool) error {
-	debugLog("Executing App-Side Join (Distributed/Skey)")
-
-	// Generate a pseudo-query for cache key generation
-	// We'll use the join context to create a unique identifier
-	cacheQuery := fmt.Sprintf("JOIN:%s", strings.Join(q.joinContext.Tables, ","))
-	cacheArgs := q.builder.whereArgs
-
-	// Check cache before executing expensive join
-	if cachedData, hit, err := q.checkCache(ctx, cacheQuery, cacheArgs); err != nil {
-		// Cache check errors are silently ignored (cache is optional)
-	} else if hit {
-		// Unmarshal to map structure first (app-side joins store table-prefixed keys)
-		var cachedResults []map[string]interface{}
-		if err := json.Unmarshal(cachedData, &cachedResults); err != nil {
-			return fmt.Errorf("failed to unmarshal cached data: %w", err)
-		}
-		
-		if dest != nil {
-			// Use scanMapsToDest to properly map table-prefixed keys to struct fields
-			return scanMapsToDest(cachedResults, dest)
-		}
-		
-		// If dest is nil, just print the results
-		if IsDebugMode() {
-			q.printResults(cachedResults, true) // true = from cache
-		}
-		return nil
-	}
-
-	// 1. Fetch T1
-	// We need to filter columns for T1
-	t1 := q.joinContext.Tables[0]
-	k1 := q.joinContext.Keys[0]
-	t2 := q.joinContext.Tables[1]
-	k2 := q.joinContext.Keys[1]
-
-	originalCols := q.builder.columns
-	var cols1, cols2 []string
-
-	if len(originalCols) > 0 {
-		for _, col := range originalCols {
-			if strings.HasPrefix(col, t2+".") {
-				cols2 = append(cols2, col)
-			} else {
-				// Assume T1 if T1 prefix or no prefix
-				cols1 = append(cols1, col)
-			}
-		}
-	}
-
-	// Ensure K1 is in cols1 (if we have specific columns)
-	if len(cols1) > 0 {
-		hasK1 := false
-		for _, col := range cols1 {
-			if col == k1 || col == t1+"."+k1 {
-				hasK1 = true
-				break
-			}
-		}
-		if !hasK1 {
-			cols1 = append(cols1, t1+"."+k1)
+		if i == 0 {
+			firstShard = shards[0]
+		} else if shards[0] != firstShard {
+			return false, nil // Different shards
 		}
-		q.builder.columns = cols1
-	}
-
-	pool1, err := q.getPool()
-	if err != nil {
-		return err
-	}
-
-	sql1, args1, err := q.builder.Build()
-	// Restore original columns just in case
-	q.builder.columns = originalCols
-	if err != nil {
-		return err
 	}
 
-	rows1, err := pool1.Pool.Query(ctx, sql1, args1...)
+	// Check for Skeys on either side of every join
+	steps, err := q.joinContext.steps()
 	if err != nil {
-		return fmt.Errorf("failed to fetch T1: %w", err)
-	}
-	defer rows1.Close()
-
-	results1, err := scanRowsToMap(rows1)
-	if err != nil {
-		return fmt.Errorf("failed to scan T1: %w", err)
-	}
-
-	if len(results1) == 0 {
-		return nil // No results
+		return false, err
 	}
-
-	// 2. Extract keys from T1 results
-	// We assume T1 joins to T2 using T1.K1 = T2.K2
-	// So we need values of K1 from T1 results
-
-	keys := make([]interface{}, 0, len(results1))
-	seen := make(map[interface{}]bool)
-
-	for _, row := range results1 {
-		if val, ok := row[k1]; ok && val != nil {
-			if !seen[val] {
-				keys = append(keys, val)
-				seen[val] = true
+	for _, s := range steps {
+		for _, side := range [][2]string{{s.table, s.column}, {s.leftTable, s.leftColumn}} {
+			tableModel, _ := registry.GetModel(side[0])
+			if tableModel == nil {
+				continue
 			}
-		}
-	}
-
-	if len(keys) == 0 {
-		return nil // No keys to join
-	}
-
-	// 3. Fetch T2
-	// We need a new query for T2
-	
-	// Ensure K2 is in cols2
-	if len(cols2) > 0 {
-		hasK2 := false
-		for _, col := range cols2 {
-			if col == k2 || col == t2+"."+k2 {
-				hasK2 = true
-				break
-			}
-		}
-		if !hasK2 {
-			cols2 = append(cols2, t2+"."+k2)
-		}
-	} else {
-		// If no specific columns for T2, select *
-		cols2 = []string{"*"}
-	}
-
-	q2 := &Query[any]{
-		builder: &QueryBuilder{
-			tableName: t2,
-			columns:   cols2,
-			whereArgs: []interface{}{},
-			queryType: "select",
-		},
-		table: t2,
-	}
-
-	// Add WHERE K2 IN (...)
-	placeholders := make([]string, len(keys))
-	for i := range keys {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-	}
-	
-	inClause := fmt.Sprintf("%s IN (%s)", k2, strings.Join(placeholders, ", "))
-	q2.Where(inClause, keys...)
-
-	pool2, err := q2.getPool()
-	if err != nil {
-		return fmt.Errorf("failed to get pool for T2: %w", err)
-	}
-
-	sql2, args2, err := q2.builder.Build()
-	if err != nil {
-		return err
-	}
-
-	rows2, err := pool2.Pool.Query(ctx, sql2, args2...)
-	if err != nil {
-		return fmt.Errorf("failed to fetch T2: %w", err)
-	}
-	defer rows2.Close()
-
-	results2, err := scanRowsToMap(rows2)
-	if err != nil {
-		return fmt.Errorf("failed to scan T2: %w", err)
-	}
-
-	// 4. Merge Results
-	// Create a map of T2 results indexed by K2 (normalized to string)
-	t2Map := make(map[string][]map[string]interface{})
-	for _, row := range results2 {
-		if val, ok := row[k2]; ok && val != nil {
-			keyStr := fmt.Sprintf("%v", val)
-			t2Map[keyStr] = append(t2Map[keyStr], row)
-		}
-	}
-
-	// Join T1 and T2
-	var joinedResults []map[string]interface{}
-	
-	for _, r1 := range results1 {
-		val := r1[k1]
-		if val == nil {
-			continue
-		}
-		keyStr := fmt.Sprintf("%v", val)
-		
-		if r2List, ok := t2Map[keyStr]; ok {
-			for _, r2 := range r2List {
-				// Merge r1 and r2
-				merged := make(map[string]interface{})
-				for k, v := range r1 {
-					if strings.Contains(k, ".") {
-						merged[k] = v
-					} else {
-						merged[t1+"."+k] = v
-					}
-				}
-				for k, v := range r2 {
-					if strings.Contains(k, ".") {
-						merged[k] = v
-					} else {
-						merged[t2+"."+k] = v
-					}
+			for _, field := range tableModel.Fields {
+				if field.Fieldname == side[1] && field.Skey != "" {
+					return false, nil // Force App-Side
 				}
-				joinedResults = append(joinedResults, merged)
 			}
 		}
 	}
 
-	// Prepare cache key
-	cacheQuery = fmt.Sprintf("JOIN:%s", strings.Join(q.joinContext.Tables, ","))
-	cacheArgs = q.builder.whereArgs
-
-	if dest != nil {
-		if err := scanMapsToDest(joinedResults, dest); err != nil {
-			return err
-		}
-		// Optimization: Cache the POPULATED struct (dest) instead of the raw map.
-		// This ensures that the cached JSON matches the struct fields (Name, Total)
-		// rather than internal map keys (users.name, orders.total), allowing json.Unmarshal to work.
-		if err := q.setCache(ctx, cacheQuery, cacheArgs, dest); err != nil {
-			// Cache set errors are silently ignored (cache is optional)
-		}
-		return nil
-	}
-
-	// Fallback: Set cache for joined results (maps) if dest is nil
-	if err := q.setCache(ctx, cacheQuery, cacheArgs, joinedResults); err != nil {
-		// Cache set errors are silently ignored (cache is optional)
-	}
-
-	// Print results (Table format) - only in debug mode
-	if IsDebugMode() {
-		fmt.Printf("\nJoined Results (%d rows):\n", len(joinedResults))
-	if len(joinedResults) > 0 {
-		// Print header
-		// Just print a few columns for demo
-		// Use keys from first row to find suitable columns to print
-		var keys []string
-		for k := range joinedResults[0] {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		
-		// Try to find fullname/bio/total/event_type
-		col1 := ""
-		col2 := ""
-		
-		for _, k := range keys {
-			if strings.Contains(k, "fullname") {
-				col1 = k
-			} else if strings.Contains(k, "bio") || strings.Contains(k, "total") || strings.Contains(k, "event_type") {
-				col2 = k
-			}
-		}
-		
-		if col1 == "" && len(keys) > 0 { col1 = keys[0] }
-		if col2 == "" && len(keys) > 1 { col2 = keys[1] }
-		
-		if col1 != "" && col2 != "" {
-			fmt.Printf("%-30s | %-30s\n", col1, col2)
-			fmt.Println(strings.Repeat("-", 65))
-			
-			for _, row := range joinedResults {
-				v1 := fmt.Sprintf("%v", row[col1])
-				v2 := fmt.Sprintf("%v", row[col2])
-				if len(v1) > 28 { v1 = v1[:25] + "..." }
-				if len(v2) > 28 { v2 = v2[:25] + "..." }
-				fmt.Printf("%-30s | %-30s\n", v1, v2)
-			}
-		}
-	}
-	}
-
-	return nil
+	return true, nil
 }
 
 // Count executes a COUNT query
@@ -1429,34 +1402,43 @@ func (q *Query[T]) Count(ctx ...context.Context) (int64, error) {
 		execCtx = ctx[0]
 	}
 
-	pool, err := q.getPool()
-	if err != nil {
-		return 0, err
-	}
-
 	// Store original query state
 	originalQueryType := q.builder.queryType
 	originalOrderBy := q.builder.orderBy
 	originalLimit := q.builder.limit
 	originalOffset := q.builder.offset
+	originalGroupBy, originalHaving, originalHavingArgs := q.builder.groupBy, q.builder.having, q.builder.havingArgs
 
-	// Modify for COUNT
+	// Modify for COUNT (counts matched rows, not groups)
 	q.builder.queryType = "select"
 	q.builder.columns = []string{"COUNT(*)"}
 	q.builder.orderBy = ""
 	q.builder.limit = 0
 	q.builder.offset = 0
+	q.builder.groupBy, q.builder.having, q.builder.havingArgs = nil, "", nil
 
 	sql, args, err := q.builder.Build()
 	if err != nil {
 		return 0, err
 	}
 
+	// Resolve routing while the builder is still a SELECT
+	var pool *driver.PGPool
+	shards := q.scatterShards()
+	if len(shards) == 0 {
+		pool, err = q.getPool()
+	}
+
 	// Restore original state
 	q.builder.queryType = originalQueryType
 	q.builder.orderBy = originalOrderBy
 	q.builder.limit = originalLimit
 	q.builder.offset = originalOffset
+	q.builder.groupBy, q.builder.having, q.builder.havingArgs = originalGroupBy, originalHaving, originalHavingArgs
+
+	if err != nil {
+		return 0, err
+	}
 
 	var count int64
 
@@ -1470,9 +1452,21 @@ func (q *Query[T]) Count(ctx ...context.Context) (int64, error) {
 		return count, nil
 	}
 
-	err = pool.Pool.QueryRow(execCtx, sql, args...).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("count query failed: %w", err)
+	// Tables spread over several shards: sum the per-shard counts
+	if len(shards) > 0 {
+		count, err = q.scatterCount(execCtx, shards, sql, args)
+		if err != nil {
+			return count, err
+		}
+	} else {
+		db, err := q.conn(execCtx, pool)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := db.QueryRow(execCtx, sql, args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("count query failed: %w", err)
+		}
 	}
 
 	// Set cache
@@ -1,127 +1,341 @@
 package engine
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/skssmd/norm/core/registry"
 	"github.com/skssmd/norm/core/utils"
 )
 
-// scanRowsToDest scans pgx.Rows into a destination (pointer to slice of structs or pointer to struct)
-func scanRowsToDest(rows pgx.Rows, dest interface{}) error {
+// ErrNoRows is returned by First and other single-row reads when no row matches
+// It is pgx.ErrNoRows, on a single pool and across shards alike.
+var ErrNoRows = pgx.ErrNoRows
+
+var (
+	mapRowType  = reflect.TypeOf(map[string]interface{}{})
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// isRowStruct reports whether t is a struct filled column by column
+// (time.Time, sql.Scanner and registered types are single values)
+func isRowStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType) && !isRegistered(t)
+}
+
+// isRowSlice reports whether a slice destination holds one element per row
+// ([]byte is a single bytea value)
+func isRowSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// scanRowsToDest scans pgx.Rows into a destination
+// dest points to a struct, map[string]interface{} or scalar (first row), to a
+// slice of those (all rows), or is a *KeyedMap (see ScanMap). Scalars need a
+// single-column result. Conversion failures are reported as *ScanError.
+func scanRowsToDest(rows pgx.Rows, dest interface{}, opts scanOptions) error {
+	if k, ok := dest.(*KeyedMap); ok {
+		results, err := scanRowsToMap(rows)
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return err
+		}
+		return k.fill(results, opts)
+	}
+
 	destValue := reflect.ValueOf(dest)
 	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
 		return errors.New("dest must be a non-nil pointer")
 	}
 
 	destElem := destValue.Elem()
-	
-	// Case 1: Slice of structs (e.g. *[]User)
-	if destElem.Kind() == reflect.Slice {
+
+	// Case 1: Slice of rows (e.g. *[]User, *[]int64, *[]map[string]interface{})
+	if isRowSlice(destElem.Type()) {
 		sliceType := destElem.Type()
 		elemType := sliceType.Elem()
-		isPtr := false
-		if elemType.Kind() == reflect.Ptr {
-			elemType = elemType.Elem()
-			isPtr = true
-		}
-
-		if elemType.Kind() != reflect.Struct {
-			return errors.New("dest slice element must be a struct or pointer to struct")
-		}
-
-		// Map columns to fields
-		fields := rows.FieldDescriptions()
-		columnMap := make([]int, len(fields)) // index in row -> index in struct field
-		
-		for i, fd := range fields {
-			colName := string(fd.Name)
-			columnMap[i] = -1
-			
-			// Find matching field
-			for j := 0; j < elemType.NumField(); j++ {
-				field := elemType.Field(j)
-				dbName := utils.ResolveColumnName(field)
-				if dbName == colName {
-					columnMap[i] = j
-					break
-				}
+
+		switch {
+		case elemType == mapRowType:
+			results, err := scanRowsToMap(rows)
+			if err != nil {
+				return err
+			}
+			destElem.Set(reflect.AppendSlice(destElem, reflect.ValueOf(results)))
+			return rows.Err()
+
+		case isRowStruct(elemType) || (elemType.Kind() == reflect.Ptr && isRowStruct(elemType.Elem())):
+			isPtr := elemType.Kind() == reflect.Ptr
+			if isPtr {
+				elemType = elemType.Elem()
+			}
+
+			// Map columns to fields once for the whole result set
+			rs, err := opts.rowScan(elemType, rows.FieldDescriptions())
+			if err != nil {
+				return err
 			}
-		}
 
-		// Iterate rows
-		for rows.Next() {
-			newElem := reflect.New(elemType).Elem()
-			scanArgs := make([]interface{}, len(fields))
-			
-			for i := range fields {
-				fieldIdx := columnMap[i]
-				if fieldIdx != -1 {
-					scanArgs[i] = newElem.Field(fieldIdx).Addr().Interface()
+			// Iterate rows
+			for rows.Next() {
+				newElem := reflect.New(elemType).Elem()
+				if err := rs.scan(rows, newElem); err != nil {
+					return err
+				}
+
+				if isPtr {
+					destElem.Set(reflect.Append(destElem, newElem.Addr()))
 				} else {
-					var ignored interface{}
-					scanArgs[i] = &ignored
+					destElem.Set(reflect.Append(destElem, newElem))
 				}
 			}
+			return rows.Err()
 
-			if err := rows.Scan(scanArgs...); err != nil {
+		default:
+			// Scalars: one column per row (e.g. SELECT id -> []int64)
+			if err := singleColumn(sliceType, len(rows.FieldDescriptions())); err != nil {
 				return err
 			}
-
-			if isPtr {
-				destElem.Set(reflect.Append(destElem, newElem.Addr()))
-			} else {
+			for rows.Next() {
+				newElem := reflect.New(elemType).Elem()
+				if err := opts.scanScalar(rows, newElem); err != nil {
+					return err
+				}
 				destElem.Set(reflect.Append(destElem, newElem))
 			}
+			return rows.Err()
 		}
-		
-		return rows.Err()
 	}
 
-	// Case 2: Single struct (e.g. *User)
-	if destElem.Kind() == reflect.Struct {
-		elemType := destElem.Type()
-		
-		if !rows.Next() {
-			if err := rows.Err(); err != nil {
-				return err
+	// Case 2: Single row (e.g. *User, *int, *map[string]interface{})
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return ErrNoRows
+	}
+
+	switch {
+	case isRowStruct(destElem.Type()):
+		rs, err := opts.rowScan(destElem.Type(), rows.FieldDescriptions())
+		if err != nil {
+			return err
+		}
+		return rs.scan(rows, destElem)
+
+	case destElem.Type() == mapRowType:
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, fd := range rows.FieldDescriptions() {
+			row[fd.Name] = values[i]
+		}
+		destElem.Set(reflect.ValueOf(row))
+		return nil
+
+	default:
+		if err := singleColumn(destElem.Type(), len(rows.FieldDescriptions())); err != nil {
+			return err
+		}
+		return opts.scanScalar(rows, destElem)
+	}
+}
+
+// scanScalar reads the single column of the current row into v (addressable)
+// Registered types with a decoder are scanned as the value pgx reads and decoded.
+func (o scanOptions) scanScalar(rows pgx.Rows, v reflect.Value) error {
+	m, _ := utils.LookupType(v.Type())
+	if m.ScanDecode() == nil {
+		if err := rows.Scan(v.Addr().Interface()); err != nil {
+			return o.scanError(rows, v.Type(), nil, err)
+		}
+		return nil
+	}
+
+	var raw interface{}
+	if err := rows.Scan(&raw); err != nil {
+		return o.scanError(rows, v.Type(), nil, err)
+	}
+	if err := setField(v, raw); err != nil {
+		fd := rows.FieldDescriptions()[0]
+		return o.errorAt(fd.Name, pgTypeName(fd.DataTypeOID), v.Type(), nil, err)
+	}
+	return nil
+}
+
+// singleColumn checks that a scalar destination gets exactly one column
+func singleColumn(t reflect.Type, columns int) error {
+	if columns != 1 {
+		return fmt.Errorf("dest %s needs a single column, got %d", t, columns)
+	}
+	return nil
+}
+
+// mapColumns returns the struct field of each result column (nil = not mapped)
+func mapColumns(plan *utils.TypePlan, fields []pgconn.FieldDescription) []*utils.FieldPlan {
+	columns := make([]*utils.FieldPlan, len(fields))
+	for i, fd := range fields {
+		columns[i] = resolveColumn(plan, fd.Name)
+	}
+	return columns
+}
+
+// resolveColumn finds the field for a result column, including qualified
+// columns ("users.email") of nested join destinations
+func resolveColumn(plan *utils.TypePlan, name string) *utils.FieldPlan {
+	if f := plan.Column(name); f != nil {
+		return f
+	}
+	if strings.Contains(name, ".") {
+		return nestedField(plan, name)
+	}
+	return nil
+}
+
+// nestedField maps "ref.column" to a column of a named struct field of the
+// destination, so struct{ User User; Order Order } is filled from users.* and
+// orders.* of a join. The field matches ref by its snake_case name (singular or
+// plural) or by the table its type is registered as.
+func nestedField(plan *utils.TypePlan, name string) *utils.FieldPlan {
+	return plan.NestedColumn(name, func() *utils.FieldPlan {
+		dot := strings.LastIndex(name, ".")
+		ref, column := name[:dot], name[dot+1:]
+		for _, g := range plan.Nested {
+			if ref != g.Column && ref != utils.Pluralize(g.Column) &&
+				ref != registry.GetRegisteredTableName(reflect.Zero(g.Elem).Interface()) {
+				continue
+			}
+			if inner := utils.PlanOf(g.Elem).Column(column); inner != nil {
+				f := *inner
+				f.Index = append(append([]int{}, g.Index...), inner.Index...)
+				f.InPointer = inner.InPointer || g.Nullable
+				return &f
 			}
-			return errors.New("no rows in result set")
 		}
+		return nil
+	})
+}
+
+// hasNested reports whether dest (pointer to a struct or slice of structs) has
+// named struct fields that joined tables can be scanned into
+func hasNested(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	plan := utils.PlanOf(t)
+	return plan != nil && len(plan.Nested) > 0
+}
 
-		fields := rows.FieldDescriptions()
-		scanArgs := make([]interface{}, len(fields))
+// structScan maps the columns of one result set onto a struct type
+type structScan struct {
+	opts    scanOptions
+	t       reflect.Type
+	fields  []pgconn.FieldDescription
+	columns []*utils.FieldPlan // result column => field (nil = discarded)
+}
 
-		for i, fd := range fields {
-			colName := string(fd.Name)
-			found := false
-			
-			for j := 0; j < elemType.NumField(); j++ {
-				field := elemType.Field(j)
-				dbName := utils.ResolveColumnName(field)
-				if dbName == colName {
-					scanArgs[i] = destElem.Field(j).Addr().Interface()
-					found = true
-					break
-				}
+// rowScan maps the result columns to the fields of struct type t
+// Strict scans fail here on unmapped columns and unfilled fields.
+func (o scanOptions) rowScan(t reflect.Type, fields []pgconn.FieldDescription) (*structScan, error) {
+	plan := utils.PlanOf(t)
+	s := &structScan{opts: o, t: t, fields: fields, columns: mapColumns(plan, fields)}
+	if o.strict {
+		if err := o.checkColumns(plan, fields, s.columns); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// scan reads the current row into elem (an addressable struct value)
+// Fields behind a struct pointer (an embedded *Base or a nested *Order) are
+// scanned into temporaries, and the pointer is only allocated when one of them
+// is not NULL, so a LEFT JOIN without a match leaves *Order nil. Registered
+// types with a decoder are scanned as the value pgx reads and decoded after.
+func (s *structScan) scan(rows pgx.Rows, elem reflect.Value) error {
+	targets := make([]interface{}, len(s.columns))
+	var deferred, decoded []int
+	for i, f := range s.columns {
+		switch {
+		case f == nil:
+			var ignored interface{}
+			targets[i] = &ignored
+		case f.Decode != nil:
+			targets[i] = new(interface{})
+			decoded = append(decoded, i)
+		case f.InPointer:
+			ptr := f.Field.Type
+			if !f.Nullable {
+				ptr = reflect.PointerTo(ptr)
 			}
-			
-			if !found {
-				var ignored interface{}
-				scanArgs[i] = &ignored
+			targets[i] = reflect.New(ptr).Interface()
+			deferred = append(deferred, i)
+		default:
+			field, err := f.Ensure(elem)
+			if err != nil {
+				return err
 			}
+			targets[i] = field.Addr().Interface()
 		}
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return s.opts.scanError(rows, s.t, s.columns, err)
+	}
 
-		return rows.Scan(scanArgs...)
+	for _, i := range deferred {
+		f, p := s.columns[i], reflect.ValueOf(targets[i]).Elem()
+		if p.IsNil() {
+			continue
+		}
+		if !f.Nullable {
+			p = p.Elem()
+		}
+		field, err := f.Ensure(elem)
+		if err != nil {
+			return err
+		}
+		field.Set(p)
 	}
 
-	return errors.New("dest must be a pointer to a struct or a slice of structs")
+	for _, i := range decoded {
+		f, raw := s.columns[i], *targets[i].(*interface{})
+		if raw == nil {
+			if !f.InPointer && !acceptsNull(f.Field.Type) {
+				return s.opts.errorAt(s.fields[i].Name, "NULL", s.t, f, errNullValue)
+			}
+			continue
+		}
+		field, err := f.Ensure(elem)
+		if err == nil {
+			err = decodeField(field, f.Decode, raw)
+		}
+		if err != nil {
+			return s.opts.errorAt(s.fields[i].Name, pgTypeName(s.fields[i].DataTypeOID), s.t, f, err)
+		}
+	}
+	return nil
 }
 
 // scanMapsToDest scans []map[string]interface{} into dest (for App-Side Joins)
-func scanMapsToDest(results []map[string]interface{}, dest interface{}) error {
+// It accepts the same destinations as scanRowsToDest.
+func scanMapsToDest(results []map[string]interface{}, dest interface{}, opts scanOptions) error {
+	if k, ok := dest.(*KeyedMap); ok {
+		return k.fill(results, opts)
+	}
+
 	destValue := reflect.ValueOf(dest)
 	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
 		return errors.New("dest must be a non-nil pointer")
@@ -129,81 +343,269 @@ func scanMapsToDest(results []map[string]interface{}, dest interface{}) error {
 
 	destElem := destValue.Elem()
 
-	if destElem.Kind() != reflect.Slice {
-		return errors.New("dest must be a pointer to a slice for join results")
+	// Single row: fill from the first row
+	if !isRowSlice(destElem.Type()) {
+		if len(results) == 0 {
+			return ErrNoRows
+		}
+		return fillValueFromMap(destElem, results[0], opts)
+	}
+
+	elemType := destElem.Type().Elem()
+	for _, row := range results {
+		newElem := reflect.New(elemType).Elem()
+		if err := fillValueFromMap(newElem, row, opts); err != nil {
+			return err
+		}
+		destElem.Set(reflect.Append(destElem, newElem))
 	}
 
-	sliceType := destElem.Type()
-	elemType := sliceType.Elem()
-	isPtr := false
-	if elemType.Kind() == reflect.Ptr {
-		elemType = elemType.Elem()
-		isPtr = true
+	return nil
+}
+
+// fillValueFromMap sets v (a struct, struct pointer, row map or scalar) from a result row
+func fillValueFromMap(v reflect.Value, row map[string]interface{}, opts scanOptions) error {
+	t := v.Type()
+	switch {
+	case isRowStruct(t):
+		return fillStructFromMap(v, row, opts)
+	case t.Kind() == reflect.Ptr && isRowStruct(t.Elem()):
+		p := reflect.New(t.Elem())
+		if err := fillStructFromMap(p.Elem(), row, opts); err != nil {
+			return err
+		}
+		v.Set(p)
+	case t == mapRowType:
+		v.Set(reflect.ValueOf(row))
+	default:
+		if err := singleColumn(t, len(row)); err != nil {
+			return err
+		}
+		for column, val := range row {
+			if val == nil && opts.strict && !acceptsNull(t) {
+				return opts.errorAt(column, "NULL", t, nil, errNullValue)
+			}
+			if err := setField(v, val); err != nil {
+				return opts.errorAt(column, valueTypeName(val), t, nil, err)
+			}
+		}
 	}
+	return nil
+}
+
+// KeyedMap is a destination that collects rows into a map keyed by a column (see ScanMap)
+type KeyedMap struct {
+	key  string
+	dest interface{}
+}
+
+// ScanMap returns a destination that fills *map[K]V with one entry per row, keyed by column key
+// V is a struct (or struct pointer), map[string]interface{}, or a scalar
+// taken from the one other column. Later rows replace earlier ones with the same key.
+// Usage:
+//
+//	byID := map[int64]User{}
+//	err := norm.Table("users").Select().All(ctx, norm.ScanMap("id", &byID))
+//
+//	emailByID := map[int64]string{}
+//	err = norm.Table("users").Select("id", "email").All(ctx, norm.ScanMap("id", &emailByID))
+func ScanMap(key string, dest interface{}) *KeyedMap {
+	return &KeyedMap{key: key, dest: dest}
+}
 
-	if elemType.Kind() != reflect.Struct {
-		return errors.New("dest slice element must be a struct or pointer to struct")
+// fill adds the rows to the destination map
+func (k *KeyedMap) fill(rows []map[string]interface{}, opts scanOptions) error {
+	v := reflect.ValueOf(k.dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Map {
+		return errors.New("ScanMap dest must be a non-nil pointer to a map")
+	}
+	m := v.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
 	}
 
-	for _, row := range results {
-		newElem := reflect.New(elemType).Elem()
+	valueType := m.Type().Elem()
+	scalar := !isRowStruct(valueType) && valueType != mapRowType &&
+		!(valueType.Kind() == reflect.Ptr && isRowStruct(valueType.Elem()))
 
-		for j := 0; j < elemType.NumField(); j++ {
-			field := elemType.Field(j)
-			dbName := utils.ResolveColumnName(field)
+	for _, row := range rows {
+		raw, ok := row[k.key]
+		if !ok {
+			return fmt.Errorf("ScanMap: column %q is not in the result", k.key)
+		}
+		key := reflect.New(m.Type().Key()).Elem()
+		if err := setField(key, raw); err != nil {
+			return opts.errorAt(k.key, valueTypeName(raw), key.Type(), nil, err)
+		}
 
-			// Try to find value in map
-			// 1. Exact match
-			if val, ok := row[dbName]; ok {
-				setField(newElem.Field(j), val)
-				continue
+		// A scalar value comes from the column next to the key
+		values := row
+		if scalar {
+			values = make(map[string]interface{}, len(row))
+			for c, val := range row {
+				if c != k.key {
+					values[c] = val
+				}
 			}
+		}
+		value := reflect.New(valueType).Elem()
+		if err := fillValueFromMap(value, values, opts); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, value)
+	}
+	return nil
+}
 
-			// 2. Tablename prefix match (e.g. "users.fullname" matches "fullname")
-			for k, v := range row {
-				if strings.HasSuffix(k, "."+dbName) {
-					setField(newElem.Field(j), v)
-					break
+// fillStructFromMap sets the fields of a struct value from a result row
+// Values that can't be stored are reported as *ScanError; strict scans also
+// fail on unused columns, unfilled fields and NULLs for non-pointer fields.
+func fillStructFromMap(elem reflect.Value, row map[string]interface{}, opts scanOptions) error {
+	plan := utils.PlanOf(elem.Type())
+
+	var used map[string]bool
+	var mapped []*utils.FieldPlan
+	if opts.strict {
+		used = make(map[string]bool, len(row))
+	}
+	set := func(f *utils.FieldPlan, column string, val interface{}) error {
+		if used != nil {
+			used[column] = true
+			mapped = append(mapped, f)
+		}
+		if val == nil {
+			// Fields behind a nil struct pointer stay unset, like a LEFT JOIN without a match
+			if used != nil && !f.InPointer && !acceptsNull(f.Field.Type) {
+				return opts.errorAt(column, "NULL", plan.Type, f, errNullValue)
+			}
+			return nil
+		}
+		field, err := f.Ensure(elem)
+		if err == nil {
+			err = setField(field, val)
+		}
+		if err != nil {
+			return opts.errorAt(column, valueTypeName(val), plan.Type, f, err)
+		}
+		return nil
+	}
+
+	for _, f := range plan.Fields {
+		// Try to find value in map
+		// 1. Exact match
+		if val, ok := row[f.Column]; ok {
+			if err := set(f, f.Column, val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 2. Tablename prefix match (e.g. "users.fullname" matches "fullname")
+		for k, v := range row {
+			if strings.HasSuffix(k, "."+f.Column) {
+				if err := set(f, k, v); err != nil {
+					return err
 				}
+				break
 			}
 		}
+	}
 
-		if isPtr {
-			destElem.Set(reflect.Append(destElem, newElem.Addr()))
-		} else {
-			destElem.Set(reflect.Append(destElem, newElem))
+	// 3. Qualified columns of nested join destinations (users.* into a User field)
+	if len(plan.Nested) > 0 {
+		for k, v := range row {
+			if !strings.Contains(k, ".") {
+				continue
+			}
+			if f := nestedField(plan, k); f != nil {
+				if err := set(f, k, v); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
-	return nil
+	if used == nil {
+		return nil
+	}
+	return opts.checkRow(plan, row, used, mapped)
 }
 
-func setField(field reflect.Value, value interface{}) {
+// setField stores a result value in field, converting between compatible types
+// (int64 to int, RFC 3339 strings to time.Time, JSON objects to structs, maps
+// and slices, any value to an sql.Scanner or a registered type). NULL leaves
+// the field unchanged.
+func setField(field reflect.Value, value interface{}) error {
 	if value == nil {
-		return
+		return nil
 	}
-	
+
 	val := reflect.ValueOf(value)
-	
+	ft := field.Type()
+
 	// Handle pointers in struct field
 	if field.Kind() == reflect.Ptr {
 		// Create new pointer
-		newPtr := reflect.New(field.Type().Elem())
+		newPtr := reflect.New(ft.Elem())
 		// Recursively set the value to the element
-		setField(newPtr.Elem(), value)
+		if err := setField(newPtr.Elem(), value); err != nil {
+			return err
+		}
 		field.Set(newPtr)
-		return
+		return nil
 	}
 
-	// Simple type conversion if needed (e.g. int64 to int)
-	if field.Type() != val.Type() {
-		if val.Type().ConvertibleTo(field.Type()) {
-			field.Set(val.Convert(field.Type()))
-		} else {
-			// Fallback: fmt.Scan? Or just ignore for now to avoid panic
-			// For basic types, ConvertibleTo handles int/float/string
-		}
-	} else {
+	if val.Type() == ft {
 		field.Set(val)
+		return nil
+	}
+
+	// Registered types (see RegisterType) decode the value themselves
+	if m, ok := utils.LookupType(ft); ok && m.Decode != nil {
+		return decodeInto(field, m.Decode, value)
+	}
+
+	// sql.NullString and friends convert the value themselves
+	if reflect.PointerTo(ft).Implements(scannerType) {
+		p := reflect.New(ft)
+		if err := p.Interface().(sql.Scanner).Scan(value); err != nil {
+			return err
+		}
+		field.Set(p.Elem())
+		return nil
+	}
+
+	switch value.(type) {
+	case string:
+		if ft == timeType {
+			t, err := time.Parse(time.RFC3339Nano, value.(string))
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+	case map[string]interface{}, []interface{}:
+		// Decoded json/jsonb values round-trip into the field's type
+		if k := ft.Kind(); k == reflect.Struct || k == reflect.Map || k == reflect.Slice {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			p := reflect.New(ft)
+			if err := json.Unmarshal(b, p.Interface()); err != nil {
+				return err
+			}
+			field.Set(p.Elem())
+			return nil
+		}
+	}
+
+	// Simple type conversion if needed (e.g. int64 to int); integers are not
+	// converted to strings, which Go would read as a rune
+	if val.Type().ConvertibleTo(ft) && !(ft.Kind() == reflect.String && (val.CanInt() || val.CanUint())) {
+		field.Set(val.Convert(ft))
+		return nil
 	}
+	return fmt.Errorf("cannot convert %T to %s", value, ft)
 }
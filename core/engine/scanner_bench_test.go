@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// benchUser has 10 columns, 7 of them with norm tags
+type benchUser struct {
+	ID        int64   `norm:"pk;auto"`
+	TenantID  int64   `norm:"skey:tenants.id"`
+	FullName  string  `norm:"name:full_name;max:100"`
+	Email     string  `norm:"unique;notnull"`
+	Bio       *string `norm:"text"`
+	Age       int32   `norm:"index"`
+	Score     float64 `norm:"default:0"`
+	Active    bool
+	CreatedAt time.Time `norm:"notnull"`
+	UpdatedAt *time.Time
+}
+
+var benchColumns = []struct {
+	name string
+	oid  uint32
+}{
+	{"id", pgtype.Int8OID},
+	{"tenant_id", pgtype.Int8OID},
+	{"full_name", pgtype.TextOID},
+	{"email", pgtype.TextOID},
+	{"bio", pgtype.TextOID},
+	{"age", pgtype.Int4OID},
+	{"score", pgtype.Float8OID},
+	{"active", pgtype.BoolOID},
+	{"created_at", pgtype.TimestamptzOID},
+	{"updated_at", pgtype.TimestamptzOID},
+}
+
+// fakeRows is an in-memory pgx.Rows over text-format values, so benchmarks
+// measure the mapping cost without a database
+type fakeRows struct {
+	fields []pgconn.FieldDescription
+	rows   [][][]byte
+	i      int
+}
+
+func newFakeRows(n int) *fakeRows {
+	r := &fakeRows{i: -1}
+	for _, c := range benchColumns {
+		r.fields = append(r.fields, pgconn.FieldDescription{Name: c.name, DataTypeOID: c.oid, Format: pgtype.TextFormatCode})
+	}
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(i + 1)
+		r.rows = append(r.rows, [][]byte{
+			[]byte(id), []byte("7"), []byte("User " + id), []byte("user" + id + "@example.com"),
+			nil, []byte("30"), []byte("4.5"), []byte("t"),
+			[]byte("2024-05-01 10:00:00+00"), nil,
+		})
+	}
+	return r
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *fakeRows) RawValues() [][]byte                          { return r.rows[r.i] }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	return pgx.ScanRow(pgTypes, r.fields, r.rows[r.i], dest...)
+}
+
+func (r *fakeRows) Values() ([]interface{}, error) {
+	values := make([]interface{}, len(r.fields))
+	for i, fd := range r.fields {
+		raw := r.rows[r.i][i]
+		if raw == nil {
+			continue
+		}
+		t, ok := pgTypes.TypeForOID(fd.DataTypeOID)
+		if !ok {
+			return nil, fmt.Errorf("unknown oid %d", fd.DataTypeOID)
+		}
+		v, err := t.Codec.DecodeValue(pgTypes, fd.DataTypeOID, fd.Format, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// benchMaps returns n rows as the map scanner receives them from joins and batches
+func benchMaps(n int) []map[string]interface{} {
+	r := newFakeRows(n)
+	var out []map[string]interface{}
+	for r.Next() {
+		values, _ := r.Values()
+		row := make(map[string]interface{}, len(values))
+		for i, fd := range r.fields {
+			row[fd.Name] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func benchmarkScanRows(b *testing.B, n int) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var users []benchUser
+		if err := scanRowsToDest(newFakeRows(n), &users, scanOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScan1Row(b *testing.B)    { benchmarkScanRows(b, 1) }
+func BenchmarkScan100Rows(b *testing.B) { benchmarkScanRows(b, 100) }
+
+func BenchmarkFillJoinRows100(b *testing.B) {
+	rows := benchMaps(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var users []benchUser
+		if err := scanMapsToDest(rows, &users, scanOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkModelExtraction(b *testing.B) {
+	bio := "hello"
+	user := &benchUser{ID: 1, TenantID: 7, FullName: "User 1", Email: "user1@example.com", Bio: &bio, Age: 30, CreatedAt: time.Now()}
+	qb := &QueryBuilder{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		qb.extractFieldsFromModel(user)
+		qb.extractAllFieldsFromModel(user)
+	}
+}
@@ -0,0 +1,738 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skssmd/norm/core/registry"
+	"github.com/skssmd/norm/core/utils"
+)
+
+// ScatterConfig controls how queries that span several shards are fanned out
+type ScatterConfig struct {
+	MaxConcurrency int  // max shards queried at once (0 = all at once)
+	AllowPartial   bool // on shard failure return results of the other shards plus a *ScatterError
+}
+
+var (
+	scatterCfg   ScatterConfig
+	scatterCfgMu sync.RWMutex
+)
+
+// SetScatterConfig sets the global fan-out configuration
+func SetScatterConfig(cfg ScatterConfig) {
+	scatterCfgMu.Lock()
+	defer scatterCfgMu.Unlock()
+	scatterCfg = cfg
+}
+
+func getScatterConfig() ScatterConfig {
+	scatterCfgMu.RLock()
+	defer scatterCfgMu.RUnlock()
+	return scatterCfg
+}
+
+// ScatterError reports the shards that failed during a fan-out query
+// When Partial is true the destination holds the results of the remaining shards.
+type ScatterError struct {
+	Table   string
+	Failed  map[string]error // shard => error
+	Partial bool
+}
+
+func (e *ScatterError) Error() string {
+	shards := make([]string, 0, len(e.Failed))
+	for s := range e.Failed {
+		shards = append(shards, s)
+	}
+	sort.Strings(shards)
+
+	parts := make([]string, 0, len(shards))
+	for _, s := range shards {
+		parts = append(parts, fmt.Sprintf("%s: %v", s, e.Failed[s]))
+	}
+	kind := "failed"
+	if e.Partial {
+		kind = "returned partial results"
+	}
+	return fmt.Sprintf("scatter query on '%s' %s (%d shard(s) failed: %s)", e.Table, kind, len(e.Failed), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes the per-shard errors to errors.Is / errors.As
+func (e *ScatterError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// AllowPartial returns results from healthy shards when some shards fail
+// The query still returns a *ScatterError describing the failed shards.
+func (q *Query[T]) AllowPartial() *Query[T] {
+	q.allowPartial = true
+	return q
+}
+
+// dataShards returns the sorted shards that hold rows of a table
+// Read/write roles describe copies of the same data and are not included.
+func dataShards(tm *registry.TableModel) []string {
+	if tm.IsKeySharded() {
+		shards := tm.Sharding.Shards()
+		sort.Strings(shards)
+		return shards
+	}
+
+	seen := make(map[string]bool)
+	var shards []string
+	for _, role := range []string{"primary", "standalone"} {
+		for s := range tm.Roles[role] {
+			if !seen[s] {
+				seen[s] = true
+				shards = append(shards, s)
+			}
+		}
+	}
+	sort.Strings(shards)
+	return shards
+}
+
+// scatterShards returns the shards a SELECT must fan out to,
+// or nil when the query can be served by a single pool
+func (q *Query[T]) scatterShards() []string {
+	if q.builder == nil || q.builder.queryType != "select" || q.rawSQL != "" || q.joinContext != nil {
+		return nil
+	}
+	if registry.GetMode() != "shard" {
+		return nil
+	}
+	tm, exists := registry.GetModel(q.table)
+	if !exists {
+		return nil
+	}
+	if tm.IsKeySharded() {
+		// A shard key error is reported when the pinned query is routed
+		if _, ok, err := q.shardKeyValue(tm); ok || err != nil {
+			return nil
+		}
+	}
+	if shards := dataShards(tm); len(shards) > 1 {
+		return shards
+	}
+	return nil
+}
+
+// shardResult is the outcome of one shard's part of a fan-out query
+type shardResult struct {
+	shard string
+	value interface{}
+	err   error
+}
+
+// fanOut runs fn for every shard with the configured concurrency and failure policy
+// It returns successful results in shard order.
+func (q *Query[T]) fanOut(ctx context.Context, shards []string, fn func(ctx context.Context, shard string) (interface{}, error)) ([]shardResult, error) {
+	cfg := getScatterConfig()
+	allowPartial := cfg.AllowPartial || q.allowPartial
+
+	limit := cfg.MaxConcurrency
+	if limit <= 0 || limit > len(shards) {
+		limit = len(shards)
+	}
+	if q.tx != nil {
+		limit = 1 // a transaction cannot be used concurrently
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	debugLog("Scatter table=%s shards=%v concurrency=%d partial=%v", q.table, shards, limit, allowPartial)
+
+	results := make([]shardResult, len(shards))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := runCtx.Err(); err != nil {
+				results[i] = shardResult{shard: shard, err: err}
+				return
+			}
+			value, err := fn(runCtx, shard)
+			results[i] = shardResult{shard: shard, value: value, err: err}
+			if err != nil && !allowPartial {
+				cancel() // fail fast: stop the remaining shards
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+
+	var ok []shardResult
+	failed := make(map[string]error)
+	for _, r := range results {
+		if r.err != nil {
+			failed[r.shard] = r.err
+			continue
+		}
+		ok = append(ok, r)
+	}
+
+	if len(failed) == 0 {
+		return ok, nil
+	}
+	if allowPartial && len(ok) > 0 {
+		return ok, &ScatterError{Table: q.table, Failed: failed, Partial: true}
+	}
+	return nil, &ScatterError{Table: q.table, Failed: failed}
+}
+
+// executeScatter runs a SELECT on every shard holding the table and merges the
+// rows, re-applying ORDER BY, LIMIT and OFFSET globally
+func (q *Query[T]) executeScatter(ctx context.Context, dest interface{}, singleRow bool, shards []string) error {
+	if q.builder.isAggregate() {
+		return q.executeScatterAggregate(ctx, dest, singleRow, shards)
+	}
+
+	if singleRow {
+		q.builder.Limit(1)
+	}
+
+	// Each shard returns its first offset+limit rows; the global window is cut after merging
+	limit, offset := q.builder.limit, q.builder.offset
+	shardBuilder := *q.builder
+	if limit > 0 {
+		shardBuilder.limit = limit + offset
+	}
+	shardBuilder.offset = 0
+
+	sql, args, err := shardBuilder.Build()
+	if err != nil {
+		return err
+	}
+
+	// Check cache
+	cacheQuery := fmt.Sprintf("SCATTER:%s|%d|%d", sql, limit, offset)
+	if cachedData, hit, _ := q.checkCache(ctx, cacheQuery, args); hit {
+		if dest != nil {
+			return json.Unmarshal(cachedData, dest)
+		}
+		var results []map[string]interface{}
+		if err := json.Unmarshal(cachedData, &results); err != nil {
+			return fmt.Errorf("failed to unmarshal cached data: %w", err)
+		}
+		if IsDebugMode() {
+			q.printResults(results, true)
+		}
+		return nil
+	}
+
+	order, err := parseOrderBy(q.builder.orderBy)
+	if err != nil {
+		return fmt.Errorf("table '%s': %w", q.table, err)
+	}
+
+	if dest == nil {
+		merged, scatterErr := q.scatterMaps(ctx, shards, sql, args, limit, offset)
+		if merged == nil && scatterErr != nil {
+			return scatterErr
+		}
+		if scatterErr == nil {
+			_ = q.setCache(ctx, cacheQuery, args, merged)
+		}
+		q.printResults(merged, false)
+		return scatterErr
+	}
+
+	// Scan each shard into a slice of the destination's element type
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("dest must be a non-nil pointer")
+	}
+	destElem := destValue.Elem()
+	sliceType := destElem.Type()
+	if destElem.Kind() != reflect.Slice {
+		sliceType = reflect.SliceOf(destElem.Type())
+	}
+
+	parts, scatterErr := q.fanOut(ctx, shards, func(ctx context.Context, shard string) (interface{}, error) {
+		return q.queryShardInto(ctx, shard, sql, args, sliceType)
+	})
+	if parts == nil {
+		return scatterErr
+	}
+
+	merged := reflect.MakeSlice(sliceType, 0, 0)
+	for _, p := range parts {
+		merged = reflect.AppendSlice(merged, p.value.(reflect.Value))
+	}
+	if err := sortSlice(merged, order, q.builder.columns); err != nil {
+		return fmt.Errorf("table '%s': %w", q.table, err)
+	}
+	merged = windowSlice(merged, limit, offset)
+
+	if destElem.Kind() == reflect.Slice {
+		// Append like the single-pool scanner does
+		destElem.Set(reflect.AppendSlice(destElem, merged))
+	} else {
+		if merged.Len() == 0 {
+			if scatterErr != nil {
+				return scatterErr
+			}
+			return ErrNoRows
+		}
+		destElem.Set(merged.Index(0))
+	}
+
+	if scatterErr == nil {
+		_ = q.setCache(ctx, cacheQuery, args, dest)
+	}
+	return scatterErr
+}
+
+// scatterMaps runs sql on every shard and merges the rows as maps,
+// applying the query's ORDER BY and the given LIMIT/OFFSET globally
+func (q *Query[T]) scatterMaps(ctx context.Context, shards []string, sql string, args []interface{}, limit, offset int) ([]map[string]interface{}, error) {
+	order, err := parseOrderBy(q.builder.orderBy)
+	if err != nil {
+		return nil, fmt.Errorf("table '%s': %w", q.table, err)
+	}
+
+	parts, scatterErr := q.fanOut(ctx, shards, func(ctx context.Context, shard string) (interface{}, error) {
+		return q.queryShardMaps(ctx, shard, sql, args)
+	})
+	if parts == nil {
+		return nil, scatterErr
+	}
+
+	merged := []map[string]interface{}{}
+	for _, p := range parts {
+		merged = append(merged, p.value.([]map[string]interface{})...)
+	}
+	if err := sortMaps(merged, order); err != nil {
+		return nil, fmt.Errorf("table '%s': %w", q.table, err)
+	}
+	return windowMaps(merged, limit, offset), scatterErr
+}
+
+// queryMaps runs the query's SELECT and returns the rows as maps
+// Queries that can't be pinned to one shard are fanned out.
+func (q *Query[T]) queryMaps(ctx context.Context) ([]map[string]interface{}, error) {
+	if shards := q.scatterShards(); len(shards) > 0 {
+		limit, offset := q.builder.limit, q.builder.offset
+		shardBuilder := *q.builder
+		if limit > 0 {
+			shardBuilder.limit = limit + offset
+		}
+		shardBuilder.offset = 0
+
+		sql, args, err := shardBuilder.Build()
+		if err != nil {
+			return nil, err
+		}
+		return q.scatterMaps(ctx, shards, sql, args, limit, offset)
+	}
+
+	pool, err := q.getPool()
+	if err != nil {
+		return nil, err
+	}
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+	return scanRowsToMap(rows)
+}
+
+// queryShardMaps runs sql on one shard and returns the rows as maps
+func (q *Query[T]) queryShardMaps(ctx context.Context, shard, sql string, args []interface{}) ([]map[string]interface{}, error) {
+	pool, err := q.poolOnShard(shard)
+	if err != nil {
+		return nil, err
+	}
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+	return scanRowsToMap(rows)
+}
+
+// queryShardInto runs sql on one shard and scans the rows into a new slice of sliceType
+func (q *Query[T]) queryShardInto(ctx context.Context, shard, sql string, args []interface{}, sliceType reflect.Type) (interface{}, error) {
+	pool, err := q.poolOnShard(shard)
+	if err != nil {
+		return nil, err
+	}
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	part := reflect.New(sliceType)
+	if err := scanRowsToDest(rows, part.Interface(), q.scanOptions()); err != nil {
+		return nil, err
+	}
+	return part.Elem(), nil
+}
+
+// scatterCount runs a COUNT query on every shard and sums the results
+func (q *Query[T]) scatterCount(ctx context.Context, shards []string, sql string, args []interface{}) (int64, error) {
+	parts, scatterErr := q.fanOut(ctx, shards, func(ctx context.Context, shard string) (interface{}, error) {
+		pool, err := q.poolOnShard(shard)
+		if err != nil {
+			return nil, err
+		}
+		db, err := q.conn(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		if err := db.QueryRow(ctx, sql, args...).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count query failed: %w", err)
+		}
+		return n, nil
+	})
+
+	var total int64
+	for _, p := range parts {
+		total += p.value.(int64)
+	}
+	return total, scatterErr
+}
+
+// --- Global ORDER BY / LIMIT / OFFSET ---
+
+// orderTerm is one column of an ORDER BY clause
+type orderTerm struct {
+	column     string
+	desc       bool
+	nullsFirst bool
+}
+
+// compare orders two values under the term (-1, 0, 1)
+// NULLs sort last in ascending and first in descending order, like Postgres,
+// unless NULLS FIRST / NULLS LAST says otherwise.
+func (t orderTerm) compare(a, b reflect.Value) int {
+	a, b = nullableValue(a), nullableValue(b)
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		if t.nullsFirst {
+			return -1
+		}
+		return 1
+	case !b.IsValid():
+		if t.nullsFirst {
+			return 1
+		}
+		return -1
+	}
+	c := compareValues(a, b)
+	if t.desc {
+		return -c
+	}
+	return c
+}
+
+var orderColumnRe = regexp.MustCompile(`^(?:\w+\.)?\w+$`)
+
+// splitOrderTerm splits "expr [ASC|DESC] [NULLS FIRST|LAST]" into its parts
+func splitOrderTerm(part string) (expr string, desc, nullsFirst bool) {
+	fields := strings.Fields(part)
+	n := len(fields)
+	nullsSet := false
+	if n >= 2 && strings.EqualFold(fields[n-2], "NULLS") {
+		switch strings.ToUpper(fields[n-1]) {
+		case "FIRST":
+			nullsFirst, nullsSet = true, true
+			n -= 2
+		case "LAST":
+			nullsSet = true
+			n -= 2
+		}
+	}
+	if n >= 2 {
+		switch strings.ToUpper(fields[n-1]) {
+		case "DESC":
+			desc = true
+			n--
+		case "ASC":
+			n--
+		}
+	}
+	if !nullsSet {
+		nullsFirst = desc
+	}
+	return strings.Join(fields[:n], " "), desc, nullsFirst
+}
+
+// parseOrderTerm parses one ORDER BY term over a plain (optionally table-qualified) column
+// Expressions can't be re-applied to merged rows and are rejected.
+func parseOrderTerm(part string) (orderTerm, error) {
+	expr, desc, nullsFirst := splitOrderTerm(part)
+	if !orderColumnRe.MatchString(expr) {
+		return orderTerm{}, fmt.Errorf("ORDER BY %q cannot be re-applied to merged rows: only plain columns can", strings.TrimSpace(part))
+	}
+	return orderTerm{column: expr, desc: desc, nullsFirst: nullsFirst}, nil
+}
+
+// parseOrderBy parses "created_at DESC, users.id" into terms (table prefixes dropped)
+func parseOrderBy(orderBy string) ([]orderTerm, error) {
+	var terms []orderTerm
+	for _, part := range strings.Split(orderBy, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		t, err := parseOrderTerm(part)
+		if err != nil {
+			return nil, err
+		}
+		t.column = bareColumn(t.column)
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// sortMaps sorts map rows by the ORDER BY terms
+// Every term must name a column of the rows.
+func sortMaps(rows []map[string]interface{}, order []orderTerm) error {
+	if len(order) == 0 || len(rows) == 0 {
+		return nil
+	}
+	for _, t := range order {
+		if _, ok := rows[0][t.column]; !ok {
+			return fmt.Errorf("ORDER BY %s cannot be re-applied to merged rows: column is not in the result", t.column)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, t := range order {
+			if c := t.compare(reflect.ValueOf(rows[i][t.column]), reflect.ValueOf(rows[j][t.column])); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// sortSlice sorts a slice of structs (or struct pointers, row maps or scalars) by the ORDER BY terms
+// Every term must map to a struct field; scalars sort by their value when
+// the term is the single selected column.
+func sortSlice(slice reflect.Value, order []orderTerm, columns []string) error {
+	if len(order) == 0 {
+		return nil
+	}
+
+	elemType := slice.Type().Elem()
+	if elemType == mapRowType {
+		return sortMaps(slice.Interface().([]map[string]interface{}), order)
+	}
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	type key struct {
+		term  orderTerm
+		field *utils.FieldPlan // nil for a scalar element
+	}
+	var keys []key
+	if isRowStruct(elemType) {
+		plan := utils.PlanOf(elemType)
+		for _, t := range order {
+			f := plan.Column(t.column)
+			if f == nil {
+				return fmt.Errorf("ORDER BY %s cannot be re-applied to merged rows: no field of %s maps to it", t.column, elemType)
+			}
+			keys = append(keys, key{term: t, field: f})
+		}
+	} else {
+		for _, t := range order {
+			if len(columns) != 1 || bareColumn(columns[0]) != t.column {
+				return fmt.Errorf("ORDER BY %s cannot be re-applied to merged rows: column is not in the result", t.column)
+			}
+			keys = append(keys, key{term: t})
+		}
+	}
+	if slice.Len() < 2 {
+		return nil
+	}
+
+	elem := func(i int) reflect.Value {
+		v := slice.Index(i)
+		if isPtr {
+			v = v.Elem()
+		}
+		return v
+	}
+	swap := reflect.Swapper(slice.Interface())
+	sort.Stable(&reflectSorter{
+		n:    slice.Len(),
+		swap: swap,
+		less: func(i, j int) bool {
+			a, b := elem(i), elem(j)
+			for _, k := range keys {
+				av, bv := a, b
+				if k.field != nil {
+					av, bv = fieldValue(a, k.field), fieldValue(b, k.field)
+				}
+				if c := k.term.compare(av, bv); c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		},
+	})
+	return nil
+}
+
+// reflectSorter adapts a reflect swapper to sort.Interface
+type reflectSorter struct {
+	n    int
+	swap func(i, j int)
+	less func(i, j int) bool
+}
+
+func (s *reflectSorter) Len() int           { return s.n }
+func (s *reflectSorter) Swap(i, j int)      { s.swap(i, j) }
+func (s *reflectSorter) Less(i, j int) bool { return s.less(i, j) }
+
+// compareValues compares two scanned values (-1, 0, 1)
+// NULLs sort last, as in ascending Postgres order.
+func compareValues(a, b reflect.Value) int {
+	a, b = nullableValue(a), nullableValue(b)
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		return 1
+	case !b.IsValid():
+		return -1
+	}
+
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if b.CanInt() {
+			return cmpOrdered(a.Int(), b.Int())
+		}
+		if b.CanFloat() {
+			return cmpOrdered(float64(a.Int()), b.Float())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if b.CanUint() {
+			return cmpOrdered(a.Uint(), b.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		if b.CanFloat() {
+			return cmpOrdered(a.Float(), b.Float())
+		}
+		if b.CanInt() {
+			return cmpOrdered(a.Float(), float64(b.Int()))
+		}
+	case reflect.String:
+		if b.Kind() == reflect.String {
+			return cmpOrdered(a.String(), b.String())
+		}
+	case reflect.Bool:
+		if b.Kind() == reflect.Bool {
+			ai, bi := 0, 0
+			if a.Bool() {
+				ai = 1
+			}
+			if b.Bool() {
+				bi = 1
+			}
+			return cmpOrdered(ai, bi)
+		}
+	}
+
+	// Fallback: compare string forms
+	return cmpOrdered(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+// nullableValue unwraps pointers and interfaces; NULL (nil) becomes the zero Value
+func nullableValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func cmpOrdered[V int | int64 | uint64 | float64 | string](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// windowMaps applies OFFSET then LIMIT to merged rows
+func windowMaps(rows []map[string]interface{}, limit, offset int) []map[string]interface{} {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return nil
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// windowSlice applies OFFSET then LIMIT to a merged reflect slice
+func windowSlice(slice reflect.Value, limit, offset int) reflect.Value {
+	n := slice.Len()
+	if offset > 0 {
+		if offset >= n {
+			return slice.Slice(0, 0)
+		}
+		slice = slice.Slice(offset, n)
+		n = slice.Len()
+	}
+	if limit > 0 && limit < n {
+		slice = slice.Slice(0, limit)
+	}
+	return slice
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOrderBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy string
+		want    []orderTerm
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"plain", "name", []orderTerm{{column: "name"}}, false},
+		{"desc nulls first by default", "created_at DESC", []orderTerm{{column: "created_at", desc: true, nullsFirst: true}}, false},
+		{"asc nulls first", "score asc nulls first", []orderTerm{{column: "score", nullsFirst: true}}, false},
+		{"desc nulls last", "score DESC NULLS LAST", []orderTerm{{column: "score", desc: true}}, false},
+		{"qualified", "users.id, name DESC", []orderTerm{{column: "id"}, {column: "name", desc: true, nullsFirst: true}}, false},
+		{"trailing comma", "id,", []orderTerm{{column: "id"}}, false},
+		{"expression", "lower(name)", nil, true},
+		{"arithmetic", "a + b DESC", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOrderBy(tt.orderBy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOrderBy(%q) error = %v, wantErr %v", tt.orderBy, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOrderBy(%q) = %+v, want %+v", tt.orderBy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	one, two := 1, 2
+	now := time.Now()
+	tests := []struct {
+		name string
+		a, b interface{}
+		want int
+	}{
+		{"ints", 1, 2, -1},
+		{"equal", int64(5), int32(5), 0},
+		{"int and float", 2, 1.5, 1},
+		{"float and int", 1.5, 2, -1},
+		{"uints", uint(3), uint64(2), 1},
+		{"strings", "b", "a", 1},
+		{"bools", false, true, -1},
+		{"times", now, now.Add(time.Second), -1},
+		{"pointers", &one, &two, -1},
+		{"null last", nil, 1, 1},
+		{"null pointer last", 1, (*int)(nil), -1},
+		{"both null", nil, (*int)(nil), 0},
+		{"mixed kinds", "10", 9, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(reflect.ValueOf(tt.a), reflect.ValueOf(tt.b)); got != tt.want {
+				t.Errorf("compareValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortSlice(t *testing.T) {
+	type row struct {
+		ID    int
+		Name  string
+		Score *int
+	}
+	score := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		order   string
+		columns []string
+		in      interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "struct fields",
+			order: "name, id DESC",
+			in:    []row{{1, "b", nil}, {2, "a", nil}, {3, "b", nil}},
+			want:  []row{{2, "a", nil}, {3, "b", nil}, {1, "b", nil}},
+		},
+		{
+			name:  "nulls last ascending",
+			order: "score",
+			in:    []row{{1, "", nil}, {2, "", score(5)}, {3, "", score(1)}},
+			want:  []row{{3, "", score(1)}, {2, "", score(5)}, {1, "", nil}},
+		},
+		{
+			name:  "nulls first descending",
+			order: "score DESC",
+			in:    []row{{1, "", score(1)}, {2, "", nil}, {3, "", score(5)}},
+			want:  []row{{2, "", nil}, {3, "", score(5)}, {1, "", score(1)}},
+		},
+		{
+			name:  "struct pointers",
+			order: "id DESC",
+			in:    []*row{{ID: 1}, {ID: 3}, {ID: 2}},
+			want:  []*row{{ID: 3}, {ID: 2}, {ID: 1}},
+		},
+		{
+			name:  "maps",
+			order: "n",
+			in:    []map[string]interface{}{{"n": 2}, {"n": 1}},
+			want:  []map[string]interface{}{{"n": 1}, {"n": 2}},
+		},
+		{
+			name:    "scalars",
+			order:   "id DESC",
+			columns: []string{"users.id"},
+			in:      []int{1, 3, 2},
+			want:    []int{3, 2, 1},
+		},
+		{
+			name:    "unknown field",
+			order:   "missing",
+			in:      []row{{ID: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "scalar of another column",
+			order:   "name",
+			columns: []string{"id"},
+			in:      []int{1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := parseOrderBy(tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slice := reflect.ValueOf(tt.in)
+			err = sortSlice(slice, order, tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortSlice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tt.in, tt.want) {
+				t.Errorf("sortSlice() = %+v, want %+v", tt.in, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowSlice(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit, offset int
+		want          []int
+	}{
+		{"none", 0, 0, []int{1, 2, 3, 4}},
+		{"limit", 2, 0, []int{1, 2}},
+		{"offset", 0, 1, []int{2, 3, 4}},
+		{"both", 2, 1, []int{2, 3}},
+		{"limit past end", 10, 2, []int{3, 4}},
+		{"offset past end", 2, 4, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := windowSlice(reflect.ValueOf([]int{1, 2, 3, 4}), tt.limit, tt.offset).Interface().([]int)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("windowSlice(limit=%d, offset=%d) = %v, want %v", tt.limit, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
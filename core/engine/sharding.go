@@ -0,0 +1,313 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/skssmd/norm/core/driver"
+	"github.com/skssmd/norm/core/registry"
+)
+
+// ErrShardKeyRequired is returned when a query on a key-sharded table cannot be
+// pinned to a single shard because the shard key value is unknown
+var ErrShardKeyRequired = errors.New("shard key value required to route query")
+
+// shardKeyValue extracts the shard key value for a key-sharded table
+// Inserts take it from the inserted fields; other queries only from a WHERE
+// equality, which names the shard holding the rows. An UPDATE may not set the
+// shard key (it would move rows across shards), and an Expression compared to
+// the shard key can't be routed.
+func (q *Query[T]) shardKeyValue(tm *registry.TableModel) (interface{}, bool, error) {
+	if q.builder == nil {
+		return nil, false, nil
+	}
+
+	if q.builder.queryType == "update" {
+		if _, ok := q.builder.updateFields[tm.ShardKey]; ok {
+			return nil, false, fmt.Errorf("table '%s': updating shard key '%s' would move rows across shards; delete and re-insert them instead", tm.TableName, tm.ShardKey)
+		}
+	}
+
+	if q.builder.queryType == "insert" {
+		if v, ok := q.builder.insertFields[tm.ShardKey]; ok {
+			return v, true, nil
+		}
+	}
+
+	v, ok := whereEqualityArg(q.builder.whereClause, q.builder.whereArgs, tm.ShardKey)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, isExpr := v.(Expression); isExpr {
+		return nil, false, fmt.Errorf("table '%s': shard key '%s' is compared to an expression and can't be routed; pass its value", tm.TableName, tm.ShardKey)
+	}
+	return v, true, nil
+}
+
+// whereEqualityArg finds a top-level "column = $N" condition and returns the Nth arg
+// Only conditions ANDed at the top level pin the shard: an equality under OR
+// or NOT, inside a subquery or within a larger expression may not hold for
+// every matching row.
+func whereEqualityArg(where string, args []interface{}, column string) (interface{}, bool) {
+	re := shardKeyPattern(column)
+	for _, cond := range topLevelConjuncts(maskLiterals(where)) {
+		m := re.FindStringSubmatch(cond)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(args) {
+			return nil, false
+		}
+		return args[n-1], true
+	}
+	return nil, false
+}
+
+var (
+	shardKeyPatterns sync.Map // column -> *regexp.Regexp
+	subqueryRe       = regexp.MustCompile(`(?i)^\s*(SELECT|WITH|VALUES)\b`)
+)
+
+// shardKeyPattern matches a whole condition "[table.]column = $N[::type]"
+func shardKeyPattern(column string) *regexp.Regexp {
+	if re, ok := shardKeyPatterns.Load(column); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`(?i)^(?:\w+\.)?` + regexp.QuoteMeta(column) + `\s*=\s*\$(\d+)(?:\s*::\s*[\w ]+)?$`)
+	actual, _ := shardKeyPatterns.LoadOrStore(column, re)
+	return actual.(*regexp.Regexp)
+}
+
+// topLevelConjuncts splits a WHERE clause into the conditions ANDed at its top level
+// Parenthesized groups of ANDs are flattened; a group holding a top-level OR
+// or a subquery contributes no conditions.
+func topLevelConjuncts(where string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(where); i++ {
+		switch c := where[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && wordAt(where, i, "OR"):
+			return nil
+		case depth == 0 && wordAt(where, i, "AND"):
+			parts = append(parts, where[start:i])
+			start = i + len("AND")
+			i = start - 1
+		}
+	}
+	parts = append(parts, where[start:])
+
+	var conds []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if inner, ok := unwrapParens(p); ok {
+			if !subqueryRe.MatchString(inner) {
+				conds = append(conds, topLevelConjuncts(inner)...)
+			}
+			continue
+		}
+		if p != "" {
+			conds = append(conds, p)
+		}
+	}
+	return conds
+}
+
+// wordAt reports whether the keyword word (any case) starts at s[i] as a whole word
+func wordAt(s string, i int, word string) bool {
+	end := i + len(word)
+	return end <= len(s) && strings.EqualFold(s[i:end], word) &&
+		(i == 0 || !isIdentByte(s[i-1])) && (end == len(s) || !isIdentByte(s[end]))
+}
+
+// unwrapParens returns the inside of s when s is a single parenthesized group
+func unwrapParens(s string) (string, bool) {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(s)-1 {
+				return "", false
+			}
+		}
+	}
+	return s[1 : len(s)-1], true
+}
+
+// maskLiterals blanks out string literals, quoted identifiers, comments and
+// dollar-quoted bodies so their contents can't be read as SQL structure
+func maskLiterals(query string) string {
+	out := []byte(query)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(out); k++ {
+			if out[k] != '\n' {
+				out[k] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		end := i + 1
+		switch {
+		case c == '\'' || c == '"':
+			end = quotedEnd(query, i, c, c == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e'))
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if nl := strings.IndexByte(query[i:], '\n'); nl >= 0 {
+				end = i + nl
+			} else {
+				end = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end = blockCommentEnd(query, i)
+		case c == '$' && (i == 0 || !isIdentByte(query[i-1])) && i+1 < len(query) && (query[i+1] < '0' || query[i+1] > '9'):
+			if tag, ok := dollarTag(query, i); ok {
+				if k := strings.Index(query[i+len(tag):], tag); k >= 0 {
+					end = i + 2*len(tag) + k
+				} else {
+					end = len(query)
+				}
+			}
+		default:
+			i++
+			continue
+		}
+		if end > i+1 {
+			blank(i, end)
+		}
+		i = end
+	}
+	return string(out)
+}
+
+// shardForKey resolves the shard for a key-sharded table from the query's shard key value
+func (q *Query[T]) shardForKey(tm *registry.TableModel) (string, error) {
+	key, ok, err := q.shardKeyValue(tm)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: table '%s' is sharded by '%s'", ErrShardKeyRequired, tm.TableName, tm.ShardKey)
+	}
+	shard, err := tm.Sharding.ShardFor(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to route table '%s' by %s=%v: %w", tm.TableName, tm.ShardKey, key, err)
+	}
+	debugLog("Shard key routing table=%s %s=%v -> shard=%s", tm.TableName, tm.ShardKey, key, shard)
+	return shard, nil
+}
+
+// keyShardedTable returns the table model if q targets a key-sharded table
+func (q *Query[T]) keyShardedTable() *registry.TableModel {
+	if q.table == "" || q.rawSQL != "" || q.joinContext != nil {
+		return nil
+	}
+	if registry.GetMode() != "shard" {
+		return nil
+	}
+	tm, exists := registry.GetModel(q.table)
+	if !exists || !tm.IsKeySharded() {
+		return nil
+	}
+	return tm
+}
+
+// poolOnShard returns the pool serving q.table on shard
+func (q *Query[T]) poolOnShard(shard string) (*driver.PGPool, error) {
+	return q.getTablePoolOnShard(registry.GetRegistryInfo(), shard, "primary")
+}
+
+// execOnAllShards runs an UPDATE/DELETE without a shard key on every shard
+// of the table and returns the total rows affected
+func (q *Query[T]) execOnAllShards(ctx context.Context, tm *registry.TableModel) (int64, error) {
+	sql, args, err := q.builder.Build()
+	if err != nil {
+		return 0, err
+	}
+
+	shards := tm.Sharding.Shards()
+	sort.Strings(shards)
+	debugLog("Broadcasting %s on table=%s to shards %v", q.builder.queryType, tm.TableName, shards)
+
+	var total int64
+	for _, shard := range shards {
+		pool, err := q.poolOnShard(shard)
+		if err != nil {
+			return total, err
+		}
+		db, err := q.conn(ctx, pool)
+		if err != nil {
+			return total, err
+		}
+		result, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return total, fmt.Errorf("query execution failed on shard '%s': %w", shard, err)
+		}
+		total += result.RowsAffected()
+	}
+	return total, nil
+}
+
+// execShardedBulk splits bulk insert rows by shard and inserts each group on its shard
+// Groups are inserted one after another; this is not atomic across shards.
+func (q *Query[T]) execShardedBulk(ctx context.Context, tm *registry.TableModel) (int64, error) {
+	keyIdx := -1
+	for i, col := range q.builder.bulkColumns {
+		if col == tm.ShardKey {
+			keyIdx = i
+			break
+		}
+	}
+	if keyIdx == -1 {
+		return 0, fmt.Errorf("%w: bulk insert into '%s' must include column '%s'", ErrShardKeyRequired, tm.TableName, tm.ShardKey)
+	}
+
+	groups := make(map[string][][]interface{})
+	targets := make(map[string][]reflect.Value)
+	for i, row := range q.builder.bulkRows {
+		shard, err := tm.Sharding.ShardFor(row[keyIdx])
+		if err != nil {
+			return 0, fmt.Errorf("failed to route bulk row by %s=%v: %w", tm.ShardKey, row[keyIdx], err)
+		}
+		groups[shard] = append(groups[shard], row)
+		if i < len(q.targets) {
+			targets[shard] = append(targets[shard], q.targets[i])
+		}
+	}
+
+	shards := make([]string, 0, len(groups))
+	for shard := range groups {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+
+	var total int64
+	for _, shard := range shards {
+		pool, err := q.poolOnShard(shard)
+		if err != nil {
+			return total, err
+		}
+		n, err := q.execBulk(ctx, pool, groups[shard], targets[shard])
+		if err != nil {
+			return total, fmt.Errorf("bulk insert failed on shard '%s': %w", shard, err)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+func TestWhereEqualityArg(t *testing.T) {
+	args := []interface{}{42, "x"}
+	tests := []struct {
+		name  string
+		where string
+		want  interface{}
+		ok    bool
+	}{
+		{"single", "tenant_id = $1", 42, true},
+		{"and", "status = $2 AND tenant_id = $1", 42, true},
+		{"nested and", "(status = $2) AND (tenant_id = $1 AND x IS NULL)", 42, true},
+		{"lower case and", "status = $2 and tenant_id = $1", 42, true},
+		{"qualified", "orders.tenant_id = $1 AND status = $2", 42, true},
+		{"cast", "tenant_id = $1::bigint", 42, true},
+		{"or", "tenant_id = $1 OR status = $2", nil, false},
+		{"or on new line", "tenant_id = $1\nOR status = $2", nil, false},
+		{"or before group", "tenant_id = $1 OR(status = $2)", nil, false},
+		{"or in group", "status = $2 AND (tenant_id = $1 OR x IS NULL)", nil, false},
+		{"not", "NOT (tenant_id = $1)", nil, false},
+		{"not equal", "tenant_id != $1", nil, false},
+		{"other column", "other_tenant_id = $1", nil, false},
+		{"expression", "tenant_id = $1 + 1", nil, false},
+		{"subquery", "id IN (SELECT id FROM t WHERE tenant_id = $1)", nil, false},
+		{"parenthesized subquery", "(SELECT tenant_id = $1)", nil, false},
+		{"string literal", "note = 'tenant_id = $1'", nil, false},
+		{"string literal with or", "note = 'a OR b' AND tenant_id = $1", 42, true},
+		{"out of range", "tenant_id = $3", nil, false},
+		{"empty", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := whereEqualityArg(tt.where, args, "tenant_id")
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("whereEqualityArg(%q) = %v, %v; want %v, %v", tt.where, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestShardKeyValue(t *testing.T) {
+	tm := &registry.TableModel{TableName: "orders", ShardKey: "tenant_id"}
+	tests := []struct {
+		name    string
+		build   func(qb *QueryBuilder)
+		want    interface{}
+		ok      bool
+		wantErr bool
+	}{
+		{"insert field", func(qb *QueryBuilder) {
+			qb.queryType = "insert"
+			qb.insertFields = map[string]interface{}{"tenant_id": 7}
+		}, 7, true, false},
+		{"where equality", func(qb *QueryBuilder) {
+			qb.queryType = "select"
+			qb.whereClause, qb.whereArgs = "tenant_id = $1", []interface{}{7}
+		}, 7, true, false},
+		{"update pinned by where", func(qb *QueryBuilder) {
+			qb.queryType = "update"
+			qb.updateFields = map[string]interface{}{"status": "paid"}
+			qb.whereClause, qb.whereArgs = "tenant_id = $1", []interface{}{7}
+		}, 7, true, false},
+		{"update without where key", func(qb *QueryBuilder) {
+			qb.queryType = "update"
+			qb.updateFields = map[string]interface{}{"status": "paid"}
+			qb.whereClause, qb.whereArgs = "id = $1", []interface{}{1}
+		}, nil, false, false},
+		{"update sets shard key", func(qb *QueryBuilder) {
+			qb.queryType = "update"
+			qb.updateFields = map[string]interface{}{"tenant_id": 5}
+			qb.whereClause, qb.whereArgs = "id = $1", []interface{}{1}
+		}, nil, false, true},
+		{"expression key", func(qb *QueryBuilder) {
+			qb.queryType = "delete"
+			qb.whereClause, qb.whereArgs = "tenant_id = $1", []interface{}{Expr("other_id")}
+		}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Query[interface{}]{builder: &QueryBuilder{tableName: "orders"}, table: "orders"}
+			tt.build(q.builder)
+			got, ok, err := q.shardKeyValue(tm)
+			if (err != nil) != tt.wantErr || ok != tt.ok || got != tt.want {
+				t.Errorf("shardKeyValue() = %v, %v, %v; want %v, %v, error %v", got, ok, err, tt.want, tt.ok, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/skssmd/norm/core/driver"
+)
+
+// cursorSeq numbers server-side cursors so nested iterations don't collide
+var cursorSeq atomic.Uint64
+
+// FetchSize streams the rows of Iter/Each through a server-side cursor, n rows per round trip
+// Without it rows are streamed from a single result set, which is fine for most
+// exports; cursors keep the server from materializing very large results at once.
+// Usage:
+//
+//	for user, err := range norm.Model(User{}).Select().FetchSize(1000).Iter(ctx) { ... }
+func (q *Query[T]) FetchSize(n int) *Query[T] {
+	q.fetchSize = n
+	return q
+}
+
+// Iter runs a SELECT (or raw query) and yields its rows one at a time
+// Rows are scanned into T: the model struct (or pointer to it) for
+// norm.Model, the struct of the model passed to Table, or
+// map[string]interface{} for string tables and raw SQL. The connection is
+// held only while the loop runs and is released when it ends or breaks.
+// Tables spread over several shards are streamed shard by shard, which
+// rules out a global ORDER BY and aggregates (use All for those).
+// Results are never cached and Preload is not applied.
+// Usage:
+//
+//	for user, err := range norm.Model(User{}).Select().Iter(ctx) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    export(user)
+//	}
+func (q *Query[T]) Iter(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if err := q.stream(ctx, yield); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// Each calls fn for every row of the query, streaming like Iter
+// Iteration stops at the first error returned by fn.
+// Usage:
+//
+//	err := norm.Model(User{}).Select().Each(ctx, func(u *User) error {
+//	    return csvWriter.Write([]string{u.Name, u.Email})
+//	})
+func (q *Query[T]) Each(ctx context.Context, fn func(*T) error) error {
+	for row, err := range q.Iter(ctx) {
+		if err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamTarget is one pool a streamed query reads from
+type streamTarget struct {
+	shard string // "" when the query isn't fanned out
+	pool  *driver.PGPool
+}
+
+// stream builds the query and yields its rows from every target pool in turn
+// A false return from yield stops the stream without an error.
+func (q *Query[T]) stream(ctx context.Context, yield func(T, error) bool) error {
+	if q.err != nil {
+		return q.err
+	}
+	if q.joinContext != nil && q.rawSQL == "" {
+		return errors.New("Iter does not support joins; use All")
+	}
+
+	dec, err := q.rowDecoder()
+	if err != nil {
+		return err
+	}
+
+	var (
+		sql     string
+		args    []interface{}
+		targets []streamTarget
+	)
+	limit, offset := 0, 0
+
+	switch {
+	case q.rawSQL != "":
+		pool, err := q.rawPool()
+		if err != nil {
+			return err
+		}
+		sql, args = q.rawSQL, q.rawArgs
+		targets = []streamTarget{{pool: pool}}
+
+	case len(q.scatterShards()) > 0:
+		if q.builder.orderBy != "" || q.builder.isAggregate() {
+			return fmt.Errorf("Iter over the shards of '%s' cannot apply ORDER BY or aggregates; use All", q.table)
+		}
+		// Each shard returns its first offset+limit rows; the window is applied while streaming
+		limit, offset = q.builder.limit, q.builder.offset
+		shardBuilder := *q.builder
+		if limit > 0 {
+			shardBuilder.limit = limit + offset
+		}
+		shardBuilder.offset = 0
+		if sql, args, err = shardBuilder.Build(); err != nil {
+			return err
+		}
+		for _, shard := range q.scatterShards() {
+			pool, err := q.poolOnShard(shard)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, streamTarget{shard: shard, pool: pool})
+		}
+
+	default:
+		pool, err := q.getPool()
+		if err != nil {
+			return err
+		}
+		if sql, args, err = q.builder.Build(); err != nil {
+			return err
+		}
+		targets = []streamTarget{{pool: pool}}
+	}
+
+	// emit applies the cross-shard window and hands a row to the caller
+	seen, stopped := 0, false
+	emit := func(row T) bool {
+		seen++
+		if seen <= offset {
+			return true
+		}
+		if !yield(row, nil) || (limit > 0 && seen == offset+limit) {
+			stopped = true
+			return false
+		}
+		return true
+	}
+
+	allowPartial := getScatterConfig().AllowPartial || q.allowPartial
+	var failed map[string]error
+	for _, t := range targets {
+		err := q.streamPool(ctx, t.pool, sql, args, dec, emit)
+		if stopped {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+		if t.shard == "" {
+			return err
+		}
+		if !allowPartial {
+			return &ScatterError{Table: q.table, Failed: map[string]error{t.shard: err}}
+		}
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[t.shard] = err
+	}
+	if len(failed) > 0 {
+		return &ScatterError{Table: q.table, Failed: failed, Partial: len(failed) < len(targets)}
+	}
+	return nil
+}
+
+// streamPool runs sql on pool and passes each decoded row to emit until it returns false
+func (q *Query[T]) streamPool(ctx context.Context, pool *driver.PGPool, sql string, args []interface{}, dec *rowDecoder[T], emit func(T) bool) error {
+	db, err := q.conn(ctx, pool)
+	if err != nil {
+		return err
+	}
+	if q.fetchSize > 0 {
+		return q.streamCursor(ctx, db, pool, sql, args, dec, emit)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	if err := dec.bind(rows.FieldDescriptions()); err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := dec.decode(rows)
+		if err != nil {
+			return err
+		}
+		if !emit(row) {
+			return nil
+		}
+	}
+	return rows.Err()
+}
+
+// streamCursor reads the result of sql through a server-side cursor, fetchSize rows at a time
+// Cursors live inside a transaction: the query's own Tx when it has one,
+// otherwise a read transaction that is rolled back once iteration ends.
+func (q *Query[T]) streamCursor(ctx context.Context, db querier, pool *driver.PGPool, sql string, args []interface{}, dec *rowDecoder[T], emit func(T) bool) error {
+	if q.tx == nil {
+		tx, err := pool.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+		if err != nil {
+			return fmt.Errorf("failed to begin cursor transaction: %w", err)
+		}
+		defer tx.Rollback(context.WithoutCancel(ctx))
+		db = tx
+	}
+
+	cursor := fmt.Sprintf("norm_cursor_%d", cursorSeq.Add(1))
+	if _, err := db.Exec(ctx, "DECLARE "+cursor+" NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		return fmt.Errorf("failed to declare cursor: %w", err)
+	}
+	if q.tx != nil {
+		// The caller's transaction outlives the loop: release the cursor explicitly
+		defer db.Exec(context.WithoutCancel(ctx), "CLOSE "+cursor)
+	}
+	debugLog("Cursor %s declared fetch=%d", cursor, q.fetchSize)
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", q.fetchSize, cursor)
+	for {
+		rows, err := db.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("cursor fetch failed: %w", err)
+		}
+		if err := dec.bind(rows.FieldDescriptions()); err != nil {
+			rows.Close()
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			row, err := dec.decode(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if !emit(row) {
+				rows.Close()
+				return nil
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < q.fetchSize {
+			return nil
+		}
+	}
+}
+
+// rowDecoder scans result rows into values of T one row at a time
+type rowDecoder[T any] struct {
+	elemType reflect.Type // struct scanned into; nil for maps
+	isPtr    bool         // T is a pointer to elemType
+	toAny    bool         // T is an interface: the struct value (or map) is boxed
+
+	opts    scanOptions
+	columns []string
+	rs      *structScan // result columns => struct fields
+}
+
+// rowDecoder picks how rows are scanned from T and the query's model
+func (q *Query[T]) rowDecoder() (*rowDecoder[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	dec := &rowDecoder[T]{opts: q.scanOptions()}
+
+	switch {
+	case t.Kind() == reflect.Struct:
+		dec.elemType = t
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		dec.elemType, dec.isPtr = t.Elem(), true
+	case t.Kind() == reflect.Interface:
+		dec.toAny = true
+		if m := reflect.TypeOf(q.model); m != nil {
+			if m.Kind() == reflect.Ptr {
+				m = m.Elem()
+			}
+			if m.Kind() == reflect.Struct {
+				dec.elemType = m
+			}
+		}
+	case t == reflect.TypeOf(map[string]interface{}{}):
+	default:
+		return nil, fmt.Errorf("Iter cannot scan rows into %s", t)
+	}
+	return dec, nil
+}
+
+// bind maps the columns of a result set to struct fields
+func (d *rowDecoder[T]) bind(fields []pgconn.FieldDescription) error {
+	d.columns = make([]string, len(fields))
+	for i, fd := range fields {
+		d.columns[i] = fd.Name
+	}
+	if d.elemType == nil {
+		return nil
+	}
+
+	rs, err := d.opts.rowScan(d.elemType, fields)
+	d.rs = rs
+	return err
+}
+
+// decode scans the current row
+func (d *rowDecoder[T]) decode(rows pgx.Rows) (T, error) {
+	var out T
+
+	if d.elemType == nil {
+		values, err := rows.Values()
+		if err != nil {
+			return out, err
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, v := range values {
+			row[d.columns[i]] = v
+		}
+		reflect.ValueOf(&out).Elem().Set(reflect.ValueOf(row))
+		return out, nil
+	}
+
+	elem := reflect.New(d.elemType)
+	if err := d.rs.scan(rows, elem.Elem()); err != nil {
+		return out, err
+	}
+
+	v := elem.Elem()
+	if d.isPtr {
+		v = elem
+	}
+	reflect.ValueOf(&out).Elem().Set(v)
+	return out, nil
+}
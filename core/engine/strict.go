@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skssmd/norm/core/utils"
+)
+
+// ScanConfig controls how result rows are mapped onto destinations
+type ScanConfig struct {
+	Strict bool // fail on result columns without a field and struct fields without a column (see Query.Strict)
+}
+
+var (
+	scanCfg   ScanConfig
+	scanCfgMu sync.RWMutex
+)
+
+// SetScanConfig sets the global scan configuration
+func SetScanConfig(cfg ScanConfig) {
+	scanCfgMu.Lock()
+	defer scanCfgMu.Unlock()
+	scanCfg = cfg
+}
+
+func getScanConfig() ScanConfig {
+	scanCfgMu.RLock()
+	defer scanCfgMu.RUnlock()
+	return scanCfg
+}
+
+var (
+	// ErrUnmappedColumn is reported by strict scans for a result column that no destination field maps to
+	ErrUnmappedColumn = errors.New("result column has no destination field")
+
+	// ErrUnfilledField is reported by strict scans for a destination field that no result column fills
+	ErrUnfilledField = errors.New("destination field has no result column")
+
+	errNullValue = errors.New("NULL cannot be stored in a non-pointer field")
+)
+
+// ScanError reports a result value that could not be stored in its destination
+// Strict scans also report unmapped columns (Err is ErrUnmappedColumn) and
+// unfilled fields (Err is ErrUnfilledField) as ScanErrors.
+type ScanError struct {
+	Table     string // queried table ("" for raw SQL)
+	Column    string // result column ("" for an unfilled field)
+	Field     string // Go field, e.g. "User.Email" ("" for an unmapped column or a scalar destination)
+	FieldType string // Go type of the field or scalar destination
+	ValueType string // Postgres type of the column, Go type of a row map value, or "NULL"
+	Err       error
+}
+
+func (e *ScanError) Error() string {
+	var b strings.Builder
+	b.WriteString("scan")
+	if e.Table != "" {
+		fmt.Fprintf(&b, " '%s'", e.Table)
+	}
+	if e.Column != "" {
+		fmt.Fprintf(&b, " column %q", e.Column)
+	}
+	if e.ValueType != "" {
+		fmt.Fprintf(&b, " (%s)", e.ValueType)
+	}
+	switch {
+	case e.Field != "":
+		fmt.Fprintf(&b, " into %s (%s)", e.Field, e.FieldType)
+	case e.FieldType != "":
+		fmt.Fprintf(&b, " into %s", e.FieldType)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+// Unwrap exposes the conversion error (or ErrUnmappedColumn / ErrUnfilledField)
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// Strict makes the scan fail on result columns that no destination field maps
+// to and on struct fields that no result column fills
+// Every problem of the result set is reported as a *ScanError (joined).
+// Usage:
+//
+//	var users []User
+//	err := norm.Table("users").Select().Strict().All(ctx, &users)
+func (q *Query[T]) Strict() *Query[T] {
+	q.strict = true
+	return q
+}
+
+// scanOptions carries the query's scan settings to the scanners
+type scanOptions struct {
+	table  string
+	strict bool
+}
+
+func (q *Query[T]) scanOptions() scanOptions {
+	return scanOptions{table: q.table, strict: q.strict || getScanConfig().Strict}
+}
+
+// errorAt builds a ScanError for a value of column that could not be stored
+// f is the field of struct type t, or nil when t is a scalar destination.
+func (o scanOptions) errorAt(column, valueType string, t reflect.Type, f *utils.FieldPlan, err error) *ScanError {
+	se := &ScanError{Table: o.table, Column: column, ValueType: valueType, FieldType: t.String(), Err: err}
+	if f != nil {
+		se.Field = fieldPath(t, f)
+		se.FieldType = f.Field.Type.String()
+	}
+	return se
+}
+
+// scanError converts a rows.Scan failure into a *ScanError
+// columns maps result columns to fields of struct type t; it is nil for a scalar t.
+func (o scanOptions) scanError(rows pgx.Rows, t reflect.Type, columns []*utils.FieldPlan, err error) error {
+	var ae pgx.ScanArgError
+	if !errors.As(err, &ae) {
+		return err
+	}
+
+	i := ae.ColumnIndex
+	valueType := ""
+	if fds := rows.FieldDescriptions(); i < len(fds) {
+		valueType = pgTypeName(fds[i].DataTypeOID)
+	}
+	if raw := rows.RawValues(); i < len(raw) && raw[i] == nil {
+		valueType = "NULL"
+	}
+
+	var f *utils.FieldPlan
+	if i < len(columns) {
+		f = columns[i]
+	}
+	return o.errorAt(ae.FieldName, valueType, t, f, ae.Err)
+}
+
+// unfilled returns an ErrUnfilledField error for every field of plan that no
+// mapped field fills; a nested struct counts as filled when any of its columns is
+func (o scanOptions) unfilled(plan *utils.TypePlan, mapped []*utils.FieldPlan) []error {
+	var errs []error
+	for _, f := range plan.Fields {
+		filled := false
+		for _, m := range mapped {
+			if m != nil && len(m.Index) >= len(f.Index) && slices.Equal(m.Index[:len(f.Index)], f.Index) {
+				filled = true
+				break
+			}
+		}
+		if !filled {
+			errs = append(errs, o.errorAt("", "", plan.Type, f, ErrUnfilledField))
+		}
+	}
+	return errs
+}
+
+// checkColumns reports the unmapped columns and unfilled fields of a pgx result set
+func (o scanOptions) checkColumns(plan *utils.TypePlan, fields []pgconn.FieldDescription, columns []*utils.FieldPlan) error {
+	var errs []error
+	for i, f := range columns {
+		if f == nil {
+			errs = append(errs, &ScanError{Table: o.table, Column: fields[i].Name, ValueType: pgTypeName(fields[i].DataTypeOID), Err: ErrUnmappedColumn})
+		}
+	}
+	errs = append(errs, o.unfilled(plan, columns)...)
+	return errors.Join(errs...)
+}
+
+// checkRow reports the unused columns and unfilled fields of a row map
+func (o scanOptions) checkRow(plan *utils.TypePlan, row map[string]interface{}, used map[string]bool, mapped []*utils.FieldPlan) error {
+	var unused []string
+	for k := range row {
+		if !used[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+
+	var errs []error
+	for _, k := range unused {
+		errs = append(errs, &ScanError{Table: o.table, Column: k, ValueType: valueTypeName(row[k]), Err: ErrUnmappedColumn})
+	}
+	errs = append(errs, o.unfilled(plan, mapped)...)
+	return errors.Join(errs...)
+}
+
+// pgTypes names the built-in Postgres types in ScanErrors
+var pgTypes = pgtype.NewMap()
+
+func pgTypeName(oid uint32) string {
+	if t, ok := pgTypes.TypeForOID(oid); ok {
+		return t.Name
+	}
+	return fmt.Sprintf("oid %d", oid)
+}
+
+// valueTypeName names the Go type of a row map value for ScanErrors
+func valueTypeName(v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// fieldPath names a field of struct type t as "User.Email"
+// Embedded structs are left out, as with Go's promoted fields.
+func fieldPath(t reflect.Type, f *utils.FieldPlan) string {
+	var parts []string
+	if t.Name() != "" {
+		parts = append(parts, t.Name())
+	}
+	for _, i := range f.Index {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		sf := t.Field(i)
+		if !sf.Anonymous {
+			parts = append(parts, sf.Name)
+		}
+		t = sf.Type
+	}
+	return strings.Join(parts, ".")
+}
+
+// acceptsNull reports whether a field of type t can hold NULL
+func acceptsNull(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return reflect.PointerTo(t).Implements(scannerType)
+}
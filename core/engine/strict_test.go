@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/skssmd/norm/core/utils"
+)
+
+type strictBase struct {
+	ID int
+}
+
+type strictUser struct {
+	strictBase
+	Email string
+}
+
+func TestScanErrorMessage(t *testing.T) {
+	conv := errors.New("cannot convert")
+	tests := []struct {
+		name string
+		err  *ScanError
+		want string
+	}{
+		{
+			"field",
+			&ScanError{Table: "users", Column: "email", ValueType: "int8", Field: "User.Email", FieldType: "string", Err: conv},
+			`scan 'users' column "email" (int8) into User.Email (string): cannot convert`,
+		},
+		{
+			"scalar",
+			&ScanError{Column: "count", ValueType: "NULL", FieldType: "int", Err: errNullValue},
+			`scan column "count" (NULL) into int: NULL cannot be stored in a non-pointer field`,
+		},
+		{
+			"unmapped column",
+			&ScanError{Table: "users", Column: "extra", ValueType: "text", Err: ErrUnmappedColumn},
+			`scan 'users' column "extra" (text): result column has no destination field`,
+		},
+		{
+			"unfilled field",
+			&ScanError{Table: "users", Field: "User.Email", FieldType: "string", Err: ErrUnfilledField},
+			`scan 'users' into User.Email (string): destination field has no result column`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+			if !errors.Is(tt.err, tt.err.Err) {
+				t.Errorf("errors.Is(err, %v) = false", tt.err.Err)
+			}
+		})
+	}
+}
+
+func TestErrorAt(t *testing.T) {
+	userType := reflect.TypeOf(strictUser{})
+	plan := utils.PlanOf(userType)
+	opts := scanOptions{table: "users"}
+	conv := errors.New("cannot convert")
+
+	tests := []struct {
+		name      string
+		typ       reflect.Type
+		column    string
+		wantField string
+		wantType  string
+	}{
+		{"field", userType, "email", "strictUser.Email", "string"},
+		{"embedded field", userType, "id", "strictUser.ID", "int"},
+		{"scalar", reflect.TypeOf(0), "", "", "int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f *utils.FieldPlan
+			if tt.column != "" {
+				if f = plan.Column(tt.column); f == nil {
+					t.Fatalf("no field maps to %q", tt.column)
+				}
+			}
+			se := opts.errorAt(tt.column, "text", tt.typ, f, conv)
+			if se.Table != "users" || se.Column != tt.column || se.ValueType != "text" || se.Err != conv {
+				t.Errorf("errorAt() = %+v", se)
+			}
+			if se.Field != tt.wantField || se.FieldType != tt.wantType {
+				t.Errorf("errorAt() field = %q (%s), want %q (%s)", se.Field, se.FieldType, tt.wantField, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestCheckRow(t *testing.T) {
+	plan := utils.PlanOf(reflect.TypeOf(strictUser{}))
+	opts := scanOptions{table: "users", strict: true}
+
+	tests := []struct {
+		name         string
+		row          map[string]interface{}
+		wantUnmapped []string // columns, sorted
+		wantUnfilled []string // fields
+	}{
+		{
+			name: "complete",
+			row:  map[string]interface{}{"id": 1, "email": "a@b"},
+		},
+		{
+			name:         "extra columns",
+			row:          map[string]interface{}{"id": 1, "email": "a@b", "z": nil, "a": 2},
+			wantUnmapped: []string{"a", "z"},
+		},
+		{
+			name:         "missing columns",
+			row:          map[string]interface{}{"id": 1},
+			wantUnfilled: []string{"strictUser.Email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[string]bool)
+			var mapped []*utils.FieldPlan
+			for k := range tt.row {
+				if f := plan.Column(k); f != nil {
+					used[k] = true
+					mapped = append(mapped, f)
+				}
+			}
+			err := opts.checkRow(plan, tt.row, used, mapped)
+
+			var unmapped, unfilled []string
+			if err != nil {
+				for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var se *ScanError
+					if !errors.As(e, &se) {
+						t.Fatalf("%v is not a *ScanError", e)
+					}
+					switch {
+					case errors.Is(se, ErrUnmappedColumn):
+						unmapped = append(unmapped, se.Column)
+					case errors.Is(se, ErrUnfilledField):
+						unfilled = append(unfilled, se.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(unmapped, tt.wantUnmapped) || !reflect.DeepEqual(unfilled, tt.wantUnfilled) {
+				t.Errorf("checkRow() unmapped %v, unfilled %v; want %v, %v", unmapped, unfilled, tt.wantUnmapped, tt.wantUnfilled)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/skssmd/norm/core/driver"
+)
+
+var (
+	// ErrTxClosed is returned when a query is run on a committed or rolled back transaction
+	ErrTxClosed = errors.New("transaction already closed")
+
+	// ErrTxPoolMismatch is returned when a query inside a transaction routes to a
+	// different pool than the one the transaction is bound to
+	ErrTxPoolMismatch = errors.New("table is not on the transaction's pool")
+)
+
+// querier is the subset of pgxpool.Pool and pgx.Tx used to run statements
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// Tx is a database transaction that queries can be built from.
+// A Tx is bound to a single pool: either explicitly when it is started with a
+// table name, or lazily by the first query that runs on it. Queries for tables
+// that live on another pool are refused with ErrTxPoolMismatch.
+// A Tx is not safe for concurrent use.
+type Tx struct {
+	opts   pgx.TxOptions
+	parent *Tx // set for nested transactions (savepoints)
+
+	mu     sync.Mutex
+	pool   *driver.PGPool
+	tx     pgx.Tx
+	closed bool
+}
+
+// Begin starts a new transaction
+// If a table is given the transaction is bound to that table's pool immediately,
+// otherwise it binds to the pool of the first query executed on it.
+func Begin(ctx context.Context, opts pgx.TxOptions, table ...string) (*Tx, error) {
+	t := &Tx{opts: opts}
+	if len(table) > 0 && table[0] != "" {
+		if err := t.bind(ctx, table[0]); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Transaction runs fn inside a transaction
+// The transaction is committed when fn returns nil and rolled back when fn
+// returns an error or panics (the panic is re-raised after rollback).
+// Usage:
+//
+//	err := Transaction(ctx, func(tx *Tx) error {
+//	    if _, err := tx.Table(order).Insert().Exec(ctx); err != nil {
+//	        return err
+//	    }
+//	    _, err := tx.Table("users").Update("balance", 0).Where("id = $1", 1).Exec(ctx)
+//	    return err
+//	})
+func Transaction(ctx context.Context, fn func(tx *Tx) error, opts ...pgx.TxOptions) error {
+	var txOpts pgx.TxOptions
+	if len(opts) > 0 {
+		txOpts = opts[0]
+	}
+	return runTx(ctx, &Tx{opts: txOpts}, fn)
+}
+
+// runTx executes fn and finishes t depending on the outcome
+func runTx(ctx context.Context, t *Tx, fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			_ = t.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err = fn(t); err != nil {
+		if rbErr := t.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return t.Commit(ctx)
+}
+
+// Begin starts a nested transaction backed by a savepoint
+// The savepoint is created when the nested transaction runs its first query.
+func (t *Tx) Begin() *Tx {
+	return &Tx{opts: t.opts, parent: t}
+}
+
+// Transaction runs fn inside a savepoint of this transaction
+// Rolling back the savepoint leaves the outer transaction usable.
+func (t *Tx) Transaction(ctx context.Context, fn func(tx *Tx) error) error {
+	return runTx(ctx, t.Begin(), fn)
+}
+
+// Table creates a query builder that runs inside this transaction
+// Accepts the same arguments as norm.Table()
+func (t *Tx) Table(args ...interface{}) *Query[any] {
+	q := &Query[any]{tx: t}
+	return q.Table(args...)
+}
+
+// Raw creates a raw SQL query that runs inside this transaction
+// The transaction must already be bound to a pool (see Begin).
+func (t *Tx) Raw(query string, args ...interface{}) *Query[any] {
+	q := &Query[any]{tx: t}
+	return q.Raw(query, args...)
+}
+
+// Commit commits the transaction (or releases the savepoint for nested transactions)
+// Committing a transaction that never ran a query is a no-op.
+func (t *Tx) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrTxClosed
+	}
+	t.closed = true
+
+	if t.tx == nil {
+		return nil
+	}
+	if err := t.tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Rollback rolls back the transaction (or to the savepoint for nested transactions)
+// Calling Rollback on a closed transaction is a no-op, so it is safe to defer.
+func (t *Tx) Rollback(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	if t.tx == nil {
+		return nil
+	}
+	if err := t.tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("failed to rollback transaction: %w", err)
+	}
+	return nil
+}
+
+// bind resolves the write pool for table and starts the transaction on it
+func (t *Tx) bind(ctx context.Context, table string) error {
+	q := &Query[any]{tx: t}
+	q.Table(table)
+	pool, err := q.getPool()
+	if err != nil {
+		return err
+	}
+	_, err = t.acquire(ctx, pool, table)
+	return err
+}
+
+// boundPool returns the pool the transaction is bound to (nil if unbound)
+func (t *Tx) boundPool() *driver.PGPool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pool == nil && t.parent != nil {
+		return t.parent.boundPool()
+	}
+	return t.pool
+}
+
+// acquire returns the pgx transaction for pool, starting it on first use
+func (t *Tx) acquire(ctx context.Context, pool *driver.PGPool, table string) (pgx.Tx, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, ErrTxClosed
+	}
+
+	if t.tx != nil {
+		if pool != t.pool {
+			return nil, fmt.Errorf("%w: '%s'", ErrTxPoolMismatch, table)
+		}
+		return t.tx, nil
+	}
+
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if t.parent != nil {
+		// Nested: make sure the outer transaction is running, then add a savepoint
+		outer, perr := t.parent.acquire(ctx, pool, table)
+		if perr != nil {
+			return nil, perr
+		}
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = pool.Pool.BeginTx(ctx, t.opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	debugLog("Transaction started for table=%s nested=%v", table, t.parent != nil)
+	t.tx = tx
+	t.pool = pool
+	return tx, nil
+}
@@ -0,0 +1,389 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+// conflictSet is an explicit SET expression of an ON CONFLICT DO UPDATE
+type conflictSet struct {
+	column string
+	expr   string        // SQL expression, e.g. "users.count + EXCLUDED.count"
+	args   []interface{} // args for placeholders in expr, numbered from $1
+}
+
+// Upsert turns an Insert or BulkInsert into an upsert on the given conflict target
+// Unless DoNothing, DoUpdate or DoUpdateSet say otherwise, every non-key column
+// being inserted is updated from EXCLUDED (keys are the primary key and the
+// target columns, read from the registered table).
+// Usage:
+//
+//	norm.Table(&user).Insert().Upsert("tenant_id", "email").Exec(ctx)
+func (q *Query[T]) Upsert(target ...string) *Query[T] {
+	q.builder.onConflict = strings.Join(target, ", ")
+	if q.builder.conflictAction == "" {
+		q.builder.conflictAction = "update"
+	}
+	return q
+}
+
+// OnConstraint uses a named constraint as the conflict target (ON CONFLICT ON CONSTRAINT name)
+func (q *Query[T]) OnConstraint(name string) *Query[T] {
+	q.builder.onConflict = ""
+	q.builder.conflictConstraint = name
+	if q.builder.conflictAction == "" {
+		q.builder.conflictAction = "update"
+	}
+	return q
+}
+
+// ConflictWhere adds the predicate of a partial unique index to the conflict target
+// Usage: Upsert("email").ConflictWhere("deleted_at IS NULL")
+func (q *Query[T]) ConflictWhere(predicate string, args ...interface{}) *Query[T] {
+	predicate, args = q.bindFragment(predicate, args)
+	q.builder.conflictTargetWhere = predicate
+	q.builder.conflictTargetArgs = args
+	return q
+}
+
+// DoNothing skips rows that conflict (ON CONFLICT ... DO NOTHING)
+func (q *Query[T]) DoNothing() *Query[T] {
+	q.builder.conflictAction = "nothing"
+	return q
+}
+
+// DoUpdate sets the given columns from the proposed row (col = EXCLUDED.col)
+// Without columns every non-key column being inserted is updated.
+func (q *Query[T]) DoUpdate(columns ...string) *Query[T] {
+	q.builder.conflictAction = "update"
+	q.builder.conflictUpdates = append(q.builder.conflictUpdates, columns...)
+	return q
+}
+
+// DoUpdateSet sets a column to an expression on conflict (placeholders start at $1)
+// The existing row is referenced by table name, the proposed one by EXCLUDED.
+// Usage:
+//
+//	Upsert("day").DoUpdateSet("hits", "page_views.hits + EXCLUDED.hits")
+func (q *Query[T]) DoUpdateSet(column, expr string, args ...interface{}) *Query[T] {
+	expr, args = q.bindFragment(expr, args)
+	q.builder.conflictAction = "update"
+	q.builder.conflictSets = append(q.builder.conflictSets, conflictSet{column: column, expr: expr, args: args})
+	return q
+}
+
+// DoUpdateWhere only updates conflicting rows matching the condition (DO UPDATE ... WHERE)
+// Usage: DoUpdateWhere("users.updated_at < EXCLUDED.updated_at")
+func (q *Query[T]) DoUpdateWhere(condition string, args ...interface{}) *Query[T] {
+	condition, args = q.bindFragment(condition, args)
+	q.builder.conflictWhere = condition
+	q.builder.conflictWhereArgs = args
+	return q
+}
+
+// Returning adds a RETURNING clause (no columns = RETURNING *)
+// On Insert and BulkInsert, Exec scans the returned rows back into the
+// structs that were inserted (passed by pointer, or as slice elements).
+// Rows are matched to structs by the conflict target or primary key
+// columns when they are returned, otherwise by position.
+// Usage:
+//
+//	users := []User{...}
+//	norm.Table("users").BulkInsert(users).Upsert("email").Returning("id", "email").Exec(ctx)
+func (q *Query[T]) Returning(cols ...string) *Query[T] {
+	q.builder.returningColumns = append([]string{}, cols...)
+	return q
+}
+
+// bindFragment resolves named parameters of a SQL fragment, deferring errors to execution
+func (q *Query[T]) bindFragment(sql string, args []interface{}) (string, []interface{}) {
+	bound, boundArgs, err := bindArgs(sql, args)
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return sql, args
+	}
+	return bound, boundArgs
+}
+
+// hasConflict reports whether the insert has an ON CONFLICT clause
+func (qb *QueryBuilder) hasConflict() bool {
+	return qb.onConflict != "" || qb.conflictConstraint != "" || qb.conflictAction == "nothing"
+}
+
+// conflictClause renders ON CONFLICT for an insert of columns; placeholders start at next
+func (qb *QueryBuilder) conflictClause(columns []string, next int) (string, []interface{}, error) {
+	if !qb.hasConflict() {
+		return "", nil, nil
+	}
+
+	var sql strings.Builder
+	var args []interface{}
+	sql.WriteString(" ON CONFLICT")
+
+	if qb.conflictConstraint != "" {
+		sql.WriteString(" ON CONSTRAINT ")
+		sql.WriteString(qb.conflictConstraint)
+	} else if qb.onConflict != "" {
+		sql.WriteString(" (")
+		sql.WriteString(qb.onConflict)
+		sql.WriteString(")")
+		if qb.conflictTargetWhere != "" {
+			sql.WriteString(" WHERE ")
+			sql.WriteString(shiftPlaceholders(qb.conflictTargetWhere, next))
+			args = append(args, qb.conflictTargetArgs...)
+			next += len(qb.conflictTargetArgs)
+		}
+	}
+
+	if qb.conflictAction == "nothing" {
+		sql.WriteString(" DO NOTHING")
+		return sql.String(), args, nil
+	}
+
+	var sets []string
+	for _, col := range qb.conflictUpdateColumns(columns) {
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+	}
+	for _, s := range qb.conflictSets {
+		sets = append(sets, fmt.Sprintf("%s = %s", s.column, shiftPlaceholders(s.expr, next)))
+		args = append(args, s.args...)
+		next += len(s.args)
+	}
+	if len(sets) == 0 {
+		return "", nil, fmt.Errorf("upsert on '%s' has no columns to update (every inserted column is a key); use DoNothing", qb.tableName)
+	}
+	sql.WriteString(" DO UPDATE SET ")
+	sql.WriteString(strings.Join(sets, ", "))
+
+	if qb.conflictWhere != "" {
+		sql.WriteString(" WHERE ")
+		sql.WriteString(shiftPlaceholders(qb.conflictWhere, next))
+		args = append(args, qb.conflictWhereArgs...)
+	}
+	return sql.String(), args, nil
+}
+
+// conflictUpdateColumns returns the columns set from EXCLUDED on conflict
+// Explicit DoUpdate columns win; with only DoUpdateSet expressions there are
+// none; otherwise every inserted column that isn't a key, in table order.
+func (qb *QueryBuilder) conflictUpdateColumns(columns []string) []string {
+	if len(qb.conflictUpdates) > 0 {
+		return qb.conflictUpdates
+	}
+	if len(qb.conflictSets) > 0 {
+		return nil
+	}
+
+	skip := make(map[string]bool)
+	for _, col := range qb.conflictTarget() {
+		skip[col] = true
+	}
+	order := columns
+	if tm, ok := registry.GetModel(qb.tableName); ok {
+		inserted := make(map[string]bool, len(columns))
+		for _, col := range columns {
+			inserted[col] = true
+		}
+		order = nil
+		for _, f := range tm.Fields {
+			if f.Pk {
+				skip[f.Fieldname] = true
+			}
+			if inserted[f.Fieldname] {
+				order = append(order, f.Fieldname)
+			}
+		}
+	}
+
+	var out []string
+	for _, col := range order {
+		if !skip[col] {
+			out = append(out, col)
+		}
+	}
+	return out
+}
+
+// conflictTarget returns the conflict target columns (none for a constraint target)
+func (qb *QueryBuilder) conflictTarget() []string {
+	if qb.onConflict == "" {
+		return nil
+	}
+	var cols []string
+	for _, c := range strings.Split(qb.onConflict, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// returningClause renders RETURNING when requested
+func (qb *QueryBuilder) returningClause() string {
+	if qb.returningColumns == nil {
+		return ""
+	}
+	if len(qb.returningColumns) == 0 {
+		return " RETURNING *"
+	}
+	return " RETURNING " + strings.Join(qb.returningColumns, ", ")
+}
+
+// returnTargets returns the structs an insert fills from RETURNING rows
+// For BulkInsert the list is aligned with the rows (invalid entries for nil pointers).
+func returnTargets(model interface{}, bulk bool) []reflect.Value {
+	v := reflect.ValueOf(model)
+	if !bulk {
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		return []reflect.Value{v.Elem()}
+	}
+
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	targets := make([]reflect.Value, v.Len())
+	for i := range targets {
+		e := v.Index(i)
+		for e.Kind() == reflect.Ptr && !e.IsNil() {
+			e = e.Elem()
+		}
+		if e.Kind() == reflect.Struct {
+			targets[i] = e
+		}
+	}
+	return targets
+}
+
+// queryReturning runs an insert with RETURNING and fills targets from the returned rows
+// rows are the inserted values (columns as in the statement), used to match returned rows.
+func (q *Query[T]) queryReturning(ctx context.Context, db querier, sql string, args []interface{}, columns []string, rows [][]interface{}, targets []reflect.Value) (int64, error) {
+	result, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer result.Close()
+
+	returned, err := scanRowsToMap(result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan returned rows: %w", err)
+	}
+	if err := q.fillReturned(returned, columns, rows, targets); err != nil {
+		return int64(len(returned)), err
+	}
+	return int64(len(returned)), nil
+}
+
+// fillReturned copies returned rows into the structs they were inserted from
+func (q *Query[T]) fillReturned(returned []map[string]interface{}, columns []string, rows [][]interface{}, targets []reflect.Value) error {
+	if len(targets) == 0 || len(returned) == 0 {
+		return nil
+	}
+
+	// Match on conflict target / primary key values when they are returned
+	keys := q.builder.conflictTarget()
+	if len(keys) == 0 {
+		if tm, ok := registry.GetModel(q.table); ok {
+			for _, f := range tm.Fields {
+				if f.Pk {
+					keys = append(keys, f.Fieldname)
+				}
+			}
+		}
+	}
+	if idx, ok := keyPositions(keys, columns, returned[0]); ok {
+		byKey := make(map[string]int, len(rows))
+		for i, row := range rows {
+			byKey[rowKey(row, idx)] = i
+		}
+		for _, ret := range returned {
+			values := make([]interface{}, len(keys))
+			for j, k := range keys {
+				values[j] = ret[k]
+			}
+			if i, found := byKey[rowKey(values, nil)]; found && targets[i].IsValid() {
+				if err := fillStructFromMap(targets[i], ret, q.scanOptions()); err != nil {
+					return fmt.Errorf("returned row %d: %w", i, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if len(returned) != len(targets) {
+		return fmt.Errorf("cannot match %d returned rows of '%s' to %d models; return the conflict or primary key columns", len(returned), q.table, len(targets))
+	}
+	for i, ret := range returned {
+		if targets[i].IsValid() {
+			if err := fillStructFromMap(targets[i], ret, q.scanOptions()); err != nil {
+				return fmt.Errorf("returned row %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// keyPositions locates key columns in the inserted columns; ok is false unless
+// every key was both inserted and returned
+func keyPositions(keys, columns []string, returned map[string]interface{}) ([]int, bool) {
+	if len(keys) == 0 {
+		return nil, false
+	}
+	idx := make([]int, len(keys))
+	for j, k := range keys {
+		idx[j] = -1
+		for i, col := range columns {
+			if col == k {
+				idx[j] = i
+			}
+		}
+		if _, ok := returned[k]; !ok || idx[j] < 0 {
+			return nil, false
+		}
+	}
+	return idx, true
+}
+
+// rowKey joins the values at idx (all values when idx is nil) into a comparable key
+func rowKey(row []interface{}, idx []int) string {
+	var parts []string
+	add := func(v interface{}) {
+		if d, ok := derefValue(v); ok {
+			parts = append(parts, fmt.Sprint(d))
+		} else {
+			parts = append(parts, "<nil>")
+		}
+	}
+	if idx == nil {
+		for _, v := range row {
+			add(v)
+		}
+	} else {
+		for _, i := range idx {
+			add(row[i])
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// insertRow returns the columns and values of a single-row insert as written
+func (qb *QueryBuilder) insertRow() ([]string, []interface{}) {
+	columns := make([]string, 0, len(qb.insertFields))
+	for col := range qb.insertFields {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = qb.insertFields[col]
+	}
+	return columns, values
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skssmd/norm/core/registry"
+)
+
+type upsertCounter struct {
+	ID    int    `norm:"pk"`
+	Day   string `norm:"unique"`
+	Hits  int
+	Label string
+}
+
+func TestConflictClause(t *testing.T) {
+	registry.Table(upsertCounter{}, "upsert_counters")
+
+	tests := []struct {
+		name     string
+		qb       QueryBuilder
+		columns  []string // inserted columns; nil means label, day, hits, id
+		next     int
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name: "no conflict",
+			qb:   QueryBuilder{tableName: "upsert_counters"},
+		},
+		{
+			name:    "do nothing without target",
+			qb:      QueryBuilder{tableName: "upsert_counters", conflictAction: "nothing"},
+			wantSQL: " ON CONFLICT DO NOTHING",
+		},
+		{
+			name:    "update non-key columns in table order",
+			qb:      QueryBuilder{tableName: "upsert_counters", onConflict: "day", conflictAction: "update"},
+			wantSQL: " ON CONFLICT (day) DO UPDATE SET hits = EXCLUDED.hits, label = EXCLUDED.label",
+		},
+		{
+			name:    "explicit columns",
+			qb:      QueryBuilder{tableName: "upsert_counters", onConflict: "day", conflictAction: "update", conflictUpdates: []string{"hits"}},
+			wantSQL: " ON CONFLICT (day) DO UPDATE SET hits = EXCLUDED.hits",
+		},
+		{
+			name:    "constraint target",
+			qb:      QueryBuilder{tableName: "upsert_counters", conflictConstraint: "counters_day_key", conflictAction: "nothing"},
+			wantSQL: " ON CONFLICT ON CONSTRAINT counters_day_key DO NOTHING",
+		},
+		{
+			name: "placeholders follow the insert",
+			qb: QueryBuilder{
+				tableName:           "upsert_counters",
+				onConflict:          "day",
+				conflictAction:      "update",
+				conflictTargetWhere: "day > $1",
+				conflictTargetArgs:  []interface{}{"2024-01-01"},
+				conflictSets:        []conflictSet{{column: "hits", expr: "upsert_counters.hits + $1", args: []interface{}{1}}},
+				conflictWhere:       "upsert_counters.label <> $1",
+				conflictWhereArgs:   []interface{}{"frozen"},
+			},
+			next:     5,
+			wantSQL:  " ON CONFLICT (day) WHERE day > $5 DO UPDATE SET hits = upsert_counters.hits + $6 WHERE upsert_counters.label <> $7",
+			wantArgs: []interface{}{"2024-01-01", 1, "frozen"},
+		},
+		{
+			name:    "only keys inserted",
+			qb:      QueryBuilder{tableName: "upsert_counters", onConflict: "day, label", conflictAction: "update"},
+			columns: []string{"id", "day", "label"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols := tt.columns
+			if cols == nil {
+				cols = []string{"label", "day", "hits", "id"}
+			}
+			sql, args, err := tt.qb.conflictClause(cols, tt.next)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("conflictClause() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("conflictClause() sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("conflictClause() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Cond is a composable WHERE condition
+// Conditions render their own placeholders, so they can be combined freely
+// without numbering $1, $2, ... by hand.
+// Usage:
+//
+//	Where(Eq("status", "active")).
+//	Where(Or(In("role", roles), IsNull("deleted_at"))).
+//	WhereNot(Like("email", "%@test.com"))
+type Cond interface {
+	// build renders the condition with placeholders starting at $start
+	build(start int) (string, []interface{}, error)
+}
+
+// --- Comparisons ---
+
+// compareCond is "<column> <op> $n"
+type compareCond struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c compareCond) build(start int) (string, []interface{}, error) {
+	return fmt.Sprintf("%s %s $%d", c.column, c.op, start), []interface{}{c.value}, nil
+}
+
+// Eq matches column = value (a nil value matches column IS NULL)
+func Eq(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compareCond{column: column, op: "=", value: value}
+}
+
+// Ne matches column <> value (a nil value matches column IS NOT NULL)
+func Ne(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return compareCond{column: column, op: "<>", value: value}
+}
+
+// Gt matches column > value
+func Gt(column string, value interface{}) Cond {
+	return compareCond{column: column, op: ">", value: value}
+}
+
+// Gte matches column >= value
+func Gte(column string, value interface{}) Cond {
+	return compareCond{column: column, op: ">=", value: value}
+}
+
+// Lt matches column < value
+func Lt(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "<", value: value}
+}
+
+// Lte matches column <= value
+func Lte(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "<=", value: value}
+}
+
+// Like matches column LIKE pattern
+func Like(column, pattern string) Cond {
+	return compareCond{column: column, op: "LIKE", value: pattern}
+}
+
+// ILike matches column ILIKE pattern (case-insensitive)
+func ILike(column, pattern string) Cond {
+	return compareCond{column: column, op: "ILIKE", value: pattern}
+}
+
+// --- IN / BETWEEN / NULL ---
+
+// inCond is "<column> [NOT] IN ($n, $n+1, ...)"
+type inCond struct {
+	column string
+	values []interface{}
+	not    bool
+}
+
+func (c inCond) build(start int) (string, []interface{}, error) {
+	if len(c.values) == 0 {
+		// IN () is invalid SQL; an empty list matches nothing (NOT IN: everything)
+		if c.not {
+			return "TRUE", nil, nil
+		}
+		return "FALSE", nil, nil
+	}
+	placeholders := make([]string, len(c.values))
+	for i := range c.values {
+		placeholders[i] = fmt.Sprintf("$%d", start+i)
+	}
+	op := "IN"
+	if c.not {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", c.column, op, strings.Join(placeholders, ", ")), c.values, nil
+}
+
+// In matches column IN (values...)
+// values may be a slice of any type or individual values: In("id", ids) or In("id", 1, 2, 3)
+func In(column string, values ...interface{}) Cond {
+	return inCond{column: column, values: flattenValues(values)}
+}
+
+// NotIn matches column NOT IN (values...)
+func NotIn(column string, values ...interface{}) Cond {
+	return inCond{column: column, values: flattenValues(values), not: true}
+}
+
+// flattenValues expands a single slice argument into its elements ([]byte is kept as a value)
+func flattenValues(values []interface{}) []interface{} {
+	if len(values) != 1 {
+		return values
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return values
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+	out := make([]interface{}, v.Len())
+	for i := range out {
+		out[i] = v.Index(i).Interface()
+	}
+	return out
+}
+
+// betweenCond is "<column> BETWEEN $n AND $n+1"
+type betweenCond struct {
+	column    string
+	low, high interface{}
+}
+
+func (c betweenCond) build(start int) (string, []interface{}, error) {
+	return fmt.Sprintf("%s BETWEEN $%d AND $%d", c.column, start, start+1), []interface{}{c.low, c.high}, nil
+}
+
+// Between matches low <= column <= high
+func Between(column string, low, high interface{}) Cond {
+	return betweenCond{column: column, low: low, high: high}
+}
+
+// nullCond is "<column> IS [NOT] NULL"
+type nullCond struct {
+	column string
+	not    bool
+}
+
+func (c nullCond) build(start int) (string, []interface{}, error) {
+	if c.not {
+		return c.column + " IS NOT NULL", nil, nil
+	}
+	return c.column + " IS NULL", nil, nil
+}
+
+// IsNull matches column IS NULL
+func IsNull(column string) Cond {
+	return nullCond{column: column}
+}
+
+// IsNotNull matches column IS NOT NULL
+func IsNotNull(column string) Cond {
+	return nullCond{column: column, not: true}
+}
+
+// --- Groups ---
+
+// groupCond joins conditions with AND / OR
+type groupCond struct {
+	op    string
+	conds []Cond
+}
+
+func (c groupCond) build(start int) (string, []interface{}, error) {
+	var parts []string
+	var args []interface{}
+	for _, cond := range c.conds {
+		if cond == nil {
+			continue
+		}
+		sql, condArgs, err := cond.build(start + len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, sql)
+		args = append(args, condArgs...)
+	}
+	switch len(parts) {
+	case 0:
+		// Empty AND is always true, empty OR always false
+		if c.op == "OR" {
+			return "FALSE", nil, nil
+		}
+		return "TRUE", nil, nil
+	case 1:
+		return parts[0], args, nil
+	}
+	return "(" + strings.Join(parts, " "+c.op+" ") + ")", args, nil
+}
+
+// And matches when every condition matches
+func And(conds ...Cond) Cond {
+	return groupCond{op: "AND", conds: conds}
+}
+
+// Or matches when any condition matches
+func Or(conds ...Cond) Cond {
+	return groupCond{op: "OR", conds: conds}
+}
+
+// notCond is "NOT (<cond>)"
+type notCond struct {
+	cond Cond
+}
+
+func (c notCond) build(start int) (string, []interface{}, error) {
+	sql, args, err := c.cond.build(start)
+	if err != nil {
+		return "", nil, err
+	}
+	return "NOT (" + sql + ")", args, nil
+}
+
+// Not negates a condition
+func Not(cond Cond) Cond {
+	return notCond{cond: cond}
+}
+
+// --- Raw SQL ---
+
+// clauseCond is a hand-written condition whose $1, $2, ... are relative to its own args
+type clauseCond struct {
+	sql  string
+	args []interface{}
+}
+
+func (c clauseCond) build(start int) (string, []interface{}, error) {
+	sql, args, err := bindArgs(c.sql, c.args)
+	if err != nil {
+		return "", nil, err
+	}
+	return shiftPlaceholders(sql, start), args, nil
+}
+
+// Clause wraps a hand-written SQL condition so it can be combined with other conditions
+// Placeholders are numbered from $1 within the clause and renumbered when combined;
+// a single P argument binds :name parameters instead.
+// Usage:
+//
+//	Or(Clause("age > $1", 18), Eq("vip", true))
+//	Clause("email = :email", P{"email": e})
+func Clause(sql string, args ...interface{}) Cond {
+	return clauseCond{sql: sql, args: args}
+}
+
+// toCond converts a Where argument (SQL string or Cond) into a Cond
+func toCond(condition interface{}, args []interface{}) (Cond, error) {
+	switch c := condition.(type) {
+	case Cond:
+		return c, nil
+	case string:
+		if strings.TrimSpace(c) == "" {
+			return nil, nil
+		}
+		return clauseCond{sql: c, args: args}, nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported WHERE condition type %T", condition)
+}
+
+// addWhere appends a condition to the WHERE clause, numbering its placeholders
+// after the args already collected
+func (qb *QueryBuilder) addWhere(op string, negate bool, condition interface{}, args []interface{}) {
+	cond, err := toCond(condition, args)
+	if err != nil {
+		qb.err = err
+		return
+	}
+	if cond == nil {
+		return
+	}
+
+	sql, condArgs, err := cond.build(len(qb.whereArgs) + 1)
+	if err != nil {
+		qb.err = err
+		return
+	}
+	if negate {
+		sql = "NOT (" + sql + ")"
+	}
+
+	if qb.whereClause == "" {
+		qb.whereClause = sql
+	} else {
+		qb.whereClause = fmt.Sprintf("%s %s %s", parenthesize(qb.whereClause), op, parenthesize(sql))
+	}
+	// Full slice expression: builder copies must not share the appended args
+	qb.whereArgs = append(qb.whereArgs[:len(qb.whereArgs):len(qb.whereArgs)], condArgs...)
+}
+
+// parenthesize wraps sql in parentheses unless it is already a single parenthesized group
+func parenthesize(sql string) string {
+	if strings.HasPrefix(sql, "(") && strings.HasSuffix(sql, ")") {
+		depth := 0
+		for i, r := range sql {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(sql)-1 {
+				return "(" + sql + ")"
+			}
+		}
+		return sql
+	}
+	return "(" + sql + ")"
+}
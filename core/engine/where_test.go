@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCondBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		cond     Cond
+		start    int
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{"eq", Eq("status", "active"), 1, "status = $1", []interface{}{"active"}, false},
+		{"eq nil", Eq("deleted_at", nil), 1, "deleted_at IS NULL", nil, false},
+		{"ne nil", Ne("deleted_at", nil), 1, "deleted_at IS NOT NULL", nil, false},
+		{"start offset", Gte("age", 18), 3, "age >= $3", []interface{}{18}, false},
+		{"ilike", ILike("email", "%@x.com"), 1, "email ILIKE $1", []interface{}{"%@x.com"}, false},
+		{"in slice", In("id", []int{1, 2}), 2, "id IN ($2, $3)", []interface{}{1, 2}, false},
+		{"in values", In("id", 1, 2, 3), 1, "id IN ($1, $2, $3)", []interface{}{1, 2, 3}, false},
+		{"in bytes", In("hash", []byte("ab")), 1, "hash IN ($1)", []interface{}{[]byte("ab")}, false},
+		{"empty in", In("id", []int{}), 1, "FALSE", nil, false},
+		{"empty not in", NotIn("id", []int{}), 1, "TRUE", nil, false},
+		{"between", Between("n", 1, 9), 4, "n BETWEEN $4 AND $5", []interface{}{1, 9}, false},
+		{
+			"and of or", And(Eq("a", 1), Or(Eq("b", 2), IsNull("c"))), 1,
+			"(a = $1 AND (b = $2 OR c IS NULL))", []interface{}{1, 2}, false,
+		},
+		{"single member group", And(Eq("a", 1)), 1, "a = $1", []interface{}{1}, false},
+		{"nil members skipped", Or(nil, Eq("a", 1), nil), 1, "a = $1", []interface{}{1}, false},
+		{"empty and", And(), 1, "TRUE", nil, false},
+		{"empty or", Or(), 1, "FALSE", nil, false},
+		{"not", Not(Eq("a", 1)), 2, "NOT (a = $2)", []interface{}{1}, false},
+		{"clause renumbered", Clause("a > $1 AND b < $2", 1, 2), 3, "a > $3 AND b < $4", []interface{}{1, 2}, false},
+		{"clause keeps literals", Clause("a = '$1' AND b = $1", 5), 2, "a = '$1' AND b = $2", []interface{}{5}, false},
+		{"named clause", Clause("a = :x OR b = :x", P{"x": 7}), 2, "a = $2 OR b = $2", []interface{}{7}, false},
+		{"named clause missing", Clause("a = :x", P{}), 1, "", nil, true},
+		{
+			"clause inside group", Or(Clause("age > $1", 18), Eq("vip", true)), 1,
+			"(age > $1 OR vip = $2)", []interface{}{18, true}, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.cond.build(tt.start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("build() sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("build() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParenthesize(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"a = 1", "(a = 1)"},
+		{"(a = 1)", "(a = 1)"},
+		{"(a = 1 OR b = 2)", "(a = 1 OR b = 2)"},
+		{"(a = 1) OR (b = 2)", "((a = 1) OR (b = 2))"},
+		{"((a = 1) AND (b = 2))", "((a = 1) AND (b = 2))"},
+		{"f(x) = 1", "(f(x) = 1)"},
+		{"(a) = f(x)", "((a) = f(x))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if got := parenthesize(tt.sql); got != tt.want {
+				t.Errorf("parenthesize(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
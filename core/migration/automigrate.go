@@ -9,6 +9,7 @@ import (
 
 	"github.com/skssmd/norm/core/driver"
 	"github.com/skssmd/norm/core/registry"
+	"github.com/skssmd/norm/core/utils"
 )
 
 // AutoMigrator handles automatic schema migration from structs
@@ -405,16 +406,9 @@ func (am *AutoMigrator) sortModelsByDependencyFromList(models []interface{}) []i
 	for _, model := range models {
 		hasForeignKey := false
 
-		t := reflect.TypeOf(model)
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-
-		// Check if model has any foreign key tags
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			normTag := field.Tag.Get("norm")
-			if strings.Contains(normTag, "fkey:") {
+		// Check if model has any foreign key tags (including embedded structs)
+		for _, f := range utils.PlanOf(reflect.TypeOf(model)).Fields {
+			if _, ok := f.Tags["fkey"]; ok {
 				hasForeignKey = true
 				break
 			}
@@ -634,7 +628,17 @@ func (am *AutoMigrator) generateCreateTableSQL(tableName, currentShard string) (
 		// Add GENERATED ALWAYS AS IDENTITY for auto-increment fields
 		// This enforces strict auto-increment and blocks manual ID insertion
 		if f.Serial && (f.Fieldtype == "BIGINT" || f.Fieldtype == "INTEGER") {
-			col = append(col, "GENERATED ALWAYS AS IDENTITY")
+			identity := "GENERATED ALWAYS AS IDENTITY"
+			// idgen:stride - the shard with ordinal i issues i+1, i+1+32, ... so keys never
+			// collide across shards, including shards added later
+			if f.IDGen == registry.IDGenStride && currentShard != "" {
+				idx, err := registry.ShardIndex(currentShard)
+				if err != nil {
+					panic("idgen:stride on " + tableName + ": " + err.Error())
+				}
+				identity += fmt.Sprintf(" (START WITH %d INCREMENT BY %d)", idx+1, registry.MaxShardOrdinal+1)
+			}
+			col = append(col, identity)
 		}
 
 		if f.Pk {
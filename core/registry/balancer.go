@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skssmd/norm/core/driver"
+)
+
+// PoolCandidate is a pool that a Balancer can pick from
+type PoolCandidate struct {
+	Name   string // registry key, e.g. "replica2"
+	Pool   *driver.PGPool
+	Weight int
+}
+
+// Balancer picks one pool out of a group of equivalent pools
+// group is the role being balanced ("replica" or "read"); candidates is never empty.
+type Balancer interface {
+	Pick(group string, candidates []PoolCandidate) *driver.PGPool
+}
+
+// --- Round robin ---
+
+// RoundRobinBalancer cycles through the candidates in registration order
+type RoundRobinBalancer struct {
+	counters sync.Map // group => *atomic.Uint64
+}
+
+// NewRoundRobinBalancer creates a round-robin balancer (the default)
+func NewRoundRobinBalancer() *RoundRobinBalancer {
+	return &RoundRobinBalancer{}
+}
+
+func (b *RoundRobinBalancer) Pick(group string, candidates []PoolCandidate) *driver.PGPool {
+	c, _ := b.counters.LoadOrStore(group, new(atomic.Uint64))
+	n := c.(*atomic.Uint64).Add(1) - 1
+	return candidates[n%uint64(len(candidates))].Pool
+}
+
+// --- Random ---
+
+// RandomBalancer picks a uniformly random candidate
+type RandomBalancer struct{}
+
+// NewRandomBalancer creates a random balancer
+func NewRandomBalancer() *RandomBalancer {
+	return &RandomBalancer{}
+}
+
+func (b *RandomBalancer) Pick(group string, candidates []PoolCandidate) *driver.PGPool {
+	return candidates[rand.IntN(len(candidates))].Pool
+}
+
+// --- Least connections ---
+
+// LeastConnBalancer picks the candidate with the fewest acquired connections
+// according to pgxpool statistics. Ties go to the earliest registered pool.
+type LeastConnBalancer struct{}
+
+// NewLeastConnBalancer creates a least-acquired-connections balancer
+func NewLeastConnBalancer() *LeastConnBalancer {
+	return &LeastConnBalancer{}
+}
+
+func (b *LeastConnBalancer) Pick(group string, candidates []PoolCandidate) *driver.PGPool {
+	best := candidates[0]
+	bestAcquired := best.Pool.Pool.Stat().AcquiredConns()
+	for _, c := range candidates[1:] {
+		if acquired := c.Pool.Pool.Stat().AcquiredConns(); acquired < bestAcquired {
+			best = c
+			bestAcquired = acquired
+		}
+	}
+	return best.Pool
+}
+
+// --- Weighted ---
+
+// WeightedBalancer distributes picks proportionally to the pool weights set
+// with ConnBuilder.Weight, using smooth weighted round robin so heavy pools
+// are interleaved with light ones instead of being picked in bursts.
+type WeightedBalancer struct {
+	mu      sync.Mutex
+	current map[string]map[string]int // group => pool name => current weight
+}
+
+// NewWeightedBalancer creates a weighted balancer
+func NewWeightedBalancer() *WeightedBalancer {
+	return &WeightedBalancer{current: make(map[string]map[string]int)}
+}
+
+func (b *WeightedBalancer) Pick(group string, candidates []PoolCandidate) *driver.PGPool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cur := b.current[group]
+	if cur == nil {
+		cur = make(map[string]int)
+		b.current[group] = cur
+	}
+
+	total := 0
+	best := -1
+	for i, c := range candidates {
+		w := c.Weight
+		if w <= 0 {
+			w = 1
+		}
+		total += w
+		cur[c.Name] += w
+		if best == -1 || cur[c.Name] > cur[candidates[best].Name] {
+			best = i
+		}
+	}
+	cur[candidates[best].Name] -= total
+	return candidates[best].Pool
+}
+
+// --- Registry integration ---
+
+// SetBalancer sets the strategy used to pick among replica/read pools
+func SetBalancer(b Balancer) {
+	norm.mu.Lock()
+	defer norm.mu.Unlock()
+	norm.balancer = b
+}
+
+// PoolGroup returns the numbered global pools registered for a role
+// ("replica" => replica1, replica2, ...; "read" => read1, read2, ...) in registration order
+func PoolGroup(role string) []PoolCandidate {
+	norm.mu.RLock()
+	defer norm.mu.RUnlock()
+	return norm.poolGroup(role)
+}
+
+// poolGroup is PoolGroup without locking (caller holds norm.mu)
+func (r *Registry) poolGroup(role string) []PoolCandidate {
+	var group []PoolCandidate
+	for name, pool := range r.pools {
+		suffix, ok := strings.CutPrefix(name, role)
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(suffix); err != nil {
+			continue
+		}
+		weight := r.weights[name]
+		if weight <= 0 {
+			weight = 1
+		}
+		group = append(group, PoolCandidate{Name: name, Pool: pool, Weight: weight})
+	}
+
+	sort.Slice(group, func(i, j int) bool {
+		ni, _ := strconv.Atoi(group[i].Name[len(role):])
+		nj, _ := strconv.Atoi(group[j].Name[len(role):])
+		return ni < nj
+	})
+	return group
+}
+
+// PickPool picks one healthy pool of the given role using the configured balancer
+// Returns nil when no healthy pool is registered for the role.
+func PickPool(role string) *driver.PGPool {
+	norm.mu.RLock()
+	all := norm.poolGroup(role)
+	balancer := norm.balancer
+	norm.mu.RUnlock()
+
+	group := all[:0]
+	for _, c := range all {
+		if IsHealthy(c.Pool) {
+			group = append(group, c)
+		}
+	}
+
+	if len(group) == 0 {
+		return nil
+	}
+	if len(group) == 1 {
+		return group[0].Pool
+	}
+	if balancer == nil {
+		balancer = defaultBalancer
+	}
+	return balancer.Pick(role, group)
+}
+
+// defaultBalancer is used when no balancer has been configured
+var defaultBalancer Balancer = NewRoundRobinBalancer()
@@ -17,6 +17,9 @@ type Registry struct {
 	mu     sync.RWMutex
 	mode   string // "" | "global" | "shard"
 	cacher Cacher
+
+	weights  map[string]int // pool name => balancing weight (replica/read pools)
+	balancer Balancer       // strategy for picking among replica/read pools
 }
 type Cacher interface {
 	Get(ctx context.Context, key string) ([]byte, error)
@@ -27,18 +30,21 @@ type Cacher interface {
 type ShardPools struct {
 	primary    *driver.PGPool
 	standalone map[string]*driver.PGPool // tableName => pool
+	ordinal    int                       // stable shard number, -1 when not set
 }
 
 // global singleton
 var norm = &Registry{
-	pools:  make(map[string]*driver.PGPool),
-	shards: make(map[string]*ShardPools),
+	pools:   make(map[string]*driver.PGPool),
+	shards:  make(map[string]*ShardPools),
+	weights: make(map[string]int),
 }
 
 // --- Connection builder ---
 type ConnBuilder struct {
-	reg *Registry
-	dsn string
+	reg    *Registry
+	dsn    string
+	weight int
 }
 
 // Register a new DSN
@@ -49,6 +55,14 @@ func Register(dsn string) *ConnBuilder {
 	}
 }
 
+// Weight sets the balancing weight of the pool being registered (default 1)
+// Only used by the weighted balancer for replica and read pools.
+// Usage: Register(dsn).Weight(3).Replica()
+func (c *ConnBuilder) Weight(w int) *ConnBuilder {
+	c.weight = w
+	return c
+}
+
 // --- Global roles ---
 
 func (c *ConnBuilder) Primary() error {
@@ -59,7 +73,7 @@ func (c *ConnBuilder) Primary() error {
 		return errors.New("cannot register global primary when shards exist")
 	}
 	if len(c.reg.pools) > 0 {
-		if _, ok := c.reg.pools["read"]; ok {
+		if len(c.reg.poolGroup("read")) > 0 {
 			return errors.New("primary cannot coexist with read/write pools")
 		}
 		if _, ok := c.reg.pools["write"]; ok {
@@ -87,7 +101,7 @@ func (c *ConnBuilder) Replica() error {
 	if c.reg.mode == "shard" {
 		return errors.New("cannot register global replica when shards exist")
 	}
-	if _, ok := c.reg.pools["read"]; ok {
+	if len(c.reg.poolGroup("read")) > 0 {
 		return errors.New("replica cannot coexist with read/write pools")
 	}
 	if _, ok := c.reg.pools["write"]; ok {
@@ -105,6 +119,7 @@ func (c *ConnBuilder) Replica() error {
 		key := fmt.Sprintf("replica%d", count)
 		if _, exists := c.reg.pools[key]; !exists {
 			c.reg.pools[key] = pool
+			c.reg.weights[key] = c.weight
 			break
 		}
 		count++
@@ -136,6 +151,7 @@ func (c *ConnBuilder) Read() error {
 		key := fmt.Sprintf("read%d", count)
 		if _, exists := c.reg.pools[key]; !exists {
 			c.reg.pools[key] = pool
+			c.reg.weights[key] = c.weight
 			break
 		}
 		count++
@@ -175,6 +191,7 @@ type ShardBuilder struct {
 	reg       *Registry
 	dsn       string
 	shardName string
+	ordinal   int // -1 when not set
 }
 
 func (c *ConnBuilder) Shard(name string) *ShardBuilder {
@@ -182,7 +199,41 @@ func (c *ConnBuilder) Shard(name string) *ShardBuilder {
 		reg:       c.reg,
 		dsn:       c.dsn,
 		shardName: name,
+		ordinal:   -1,
+	}
+}
+
+// MaxShardOrdinal is the highest shard ordinal; snowflake keys hold 5 bits of shard
+const MaxShardOrdinal = 31
+
+// Ordinal sets the stable number of the shard (0-31), used by the snowflake
+// and stride key generators. It must be unique across shards and must not
+// change once keys were issued, so adding or renaming shards keeps keys apart.
+// Usage: Register(dsn).Shard("shard1").Ordinal(0).Primary()
+func (s *ShardBuilder) Ordinal(n int) *ShardBuilder {
+	s.ordinal = n
+	return s
+}
+
+// setOrdinal records the builder's ordinal on its shard; the caller holds the lock
+func (s *ShardBuilder) setOrdinal() error {
+	if s.ordinal < 0 {
+		return nil
+	}
+	if s.ordinal > MaxShardOrdinal {
+		return fmt.Errorf("shard '%s': ordinal %d out of range 0-%d", s.shardName, s.ordinal, MaxShardOrdinal)
 	}
+	for name, shard := range s.reg.shards {
+		if name != s.shardName && shard.ordinal == s.ordinal {
+			return fmt.Errorf("shard '%s': ordinal %d is already used by shard '%s'", s.shardName, s.ordinal, name)
+		}
+	}
+	shard := s.reg.shards[s.shardName]
+	if shard.ordinal >= 0 && shard.ordinal != s.ordinal {
+		return fmt.Errorf("shard '%s' already has ordinal %d", s.shardName, shard.ordinal)
+	}
+	shard.ordinal = s.ordinal
+	return nil
 }
 
 func (s *ShardBuilder) Primary() error {
@@ -196,6 +247,7 @@ func (s *ShardBuilder) Primary() error {
 	if s.reg.shards[s.shardName] == nil {
 		s.reg.shards[s.shardName] = &ShardPools{
 			standalone: make(map[string]*driver.PGPool),
+			ordinal:    -1,
 		}
 	}
 
@@ -207,6 +259,9 @@ func (s *ShardBuilder) Primary() error {
 	if len(s.reg.shards[s.shardName].standalone) > 0 {
 		return fmt.Errorf("cannot register primary for shard '%s': standalone pools already exist (shard cannot be both primary and standalone)", s.shardName)
 	}
+	if err := s.setOrdinal(); err != nil {
+		return err
+	}
 
 	pool, err := driver.Connect(s.dsn)
 	if err != nil {
@@ -229,6 +284,7 @@ func (s *ShardBuilder) Standalone(tables ...string) error {
 	if s.reg.shards[s.shardName] == nil {
 		s.reg.shards[s.shardName] = &ShardPools{
 			standalone: make(map[string]*driver.PGPool),
+			ordinal:    -1,
 		}
 	}
 
@@ -236,6 +292,9 @@ func (s *ShardBuilder) Standalone(tables ...string) error {
 	if s.reg.shards[s.shardName].primary != nil {
 		return fmt.Errorf("cannot register standalone for shard '%s': primary pool already exists (shard cannot be both primary and standalone)", s.shardName)
 	}
+	if err := s.setOrdinal(); err != nil {
+		return err
+	}
 
 	pool, err := driver.Connect(s.dsn)
 	if err != nil {
@@ -298,6 +357,9 @@ func GetRegistryInfo() map[string]interface{} {
 		sInfo := make(map[string]interface{})
 		sInfo["has_primary"] = shardPools.primary != nil
 		sInfo["primary_pool"] = shardPools.primary
+		if shardPools.ordinal >= 0 {
+			sInfo["ordinal"] = shardPools.ordinal
+		}
 
 		// Return actual pool references for standalone pools
 		standalonePools := make(map[string]*driver.PGPool)
@@ -338,6 +400,9 @@ func GetMode() string {
 // Reset closes all connections and clears the registry
 // Useful for testing scenarios
 func Reset() {
+	// Stop health checks first: the monitor takes the registry lock
+	resetHealth()
+
 	norm.mu.Lock()
 	defer norm.mu.Unlock()
 
@@ -346,6 +411,7 @@ func Reset() {
 		pool.Close()
 	}
 	norm.pools = make(map[string]*driver.PGPool)
+	norm.weights = make(map[string]int)
 
 	// Close shard pools
 	for _, shard := range norm.shards {
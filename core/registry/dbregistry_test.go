@@ -0,0 +1,37 @@
+package registry
+
+import "testing"
+
+func TestShardOrdinal(t *testing.T) {
+	reg := &Registry{shards: map[string]*ShardPools{
+		"shard1": {ordinal: 0},
+		"shard2": {ordinal: -1},
+	}}
+
+	tests := []struct {
+		name    string
+		shard   string
+		ordinal int
+		want    int
+		wantErr bool
+	}{
+		{"unset leaves the shard alone", "shard2", -1, -1, false},
+		{"new ordinal", "shard2", 1, 1, false},
+		{"same ordinal again", "shard2", 1, 1, false},
+		{"changed ordinal", "shard2", 2, 1, true},
+		{"taken by another shard", "shard2", 0, 1, true},
+		{"out of range", "shard1", MaxShardOrdinal + 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ShardBuilder{reg: reg, shardName: tt.shard, ordinal: tt.ordinal}
+			err := s.setOrdinal()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setOrdinal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := reg.shards[tt.shard].ordinal; got != tt.want {
+				t.Errorf("ordinal = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
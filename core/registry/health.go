@@ -0,0 +1,260 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skssmd/norm/core/driver"
+)
+
+var (
+	// ErrNoWritablePool is returned when every pool that can accept writes for a query is down
+	ErrNoWritablePool = errors.New("no writable pool available")
+
+	// ErrNoReadablePool is returned when every pool that can serve a read is down
+	ErrNoReadablePool = errors.New("no readable pool available")
+)
+
+// HealthConfig configures the background health monitor
+type HealthConfig struct {
+	Interval         time.Duration    // time between checks (default 5s)
+	Timeout          time.Duration    // ping timeout per pool (default 2s)
+	FailureThreshold int              // consecutive failed pings before a pool is marked down (default 3)
+	SuccessThreshold int              // consecutive successful pings before a pool is marked up again (default 2)
+	OnChange         func(PoolHealth) // called when a pool is marked down or up (nil = silent)
+}
+
+// PoolHealth is the health status of a single pool
+type PoolHealth struct {
+	Name                 string // e.g. "primary", "replica2", "shard1:primary", "shard2:standalone:analytics"
+	Writable             bool   // whether the pool accepts writes
+	Healthy              bool
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	LastError            string
+	LastCheck            time.Time
+}
+
+// healthMonitor tracks pool health; pools without a record are considered healthy
+type healthMonitor struct {
+	mu     sync.RWMutex
+	status map[*driver.PGPool]*PoolHealth
+	cfg    HealthConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var health = &healthMonitor{
+	status: make(map[*driver.PGPool]*PoolHealth),
+}
+
+// StartHealthMonitor starts pinging every registered pool in the background
+// Calling it again restarts the monitor with the new configuration.
+func StartHealthMonitor(cfg HealthConfig) {
+	StopHealthMonitor()
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	health.mu.Lock()
+	health.cfg = cfg
+	health.cancel = cancel
+	health.done = done
+	health.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		CheckHealth(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+// StopHealthMonitor stops the background monitor (statuses are kept)
+func StopHealthMonitor() {
+	health.mu.Lock()
+	cancel, done := health.cancel, health.done
+	health.cancel, health.done = nil, nil
+	health.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// CheckHealth pings every registered pool once and updates their status
+// It is run periodically by the monitor but can also be called directly.
+func CheckHealth(ctx context.Context) {
+	health.mu.RLock()
+	cfg := health.cfg
+	health.mu.RUnlock()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = 2
+	}
+
+	targets := monitoredPools()
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(t monitoredPool) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+			err := t.pool.Pool.Ping(pingCtx)
+			if ctx.Err() != nil {
+				return // monitor stopped mid-check, don't record a false failure
+			}
+			if changed, ok := health.record(t, err, cfg); ok && cfg.OnChange != nil {
+				cfg.OnChange(changed)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+// record applies one ping result with hysteresis
+// It returns the pool's new status when the pool was marked down or up.
+func (h *healthMonitor) record(t monitoredPool, err error, cfg HealthConfig) (PoolHealth, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st, ok := h.status[t.pool]
+	if !ok {
+		st = &PoolHealth{Name: t.name, Writable: t.writable, Healthy: true}
+		h.status[t.pool] = st
+	}
+	st.LastCheck = time.Now()
+
+	if err != nil {
+		st.ConsecutiveFailures++
+		st.ConsecutiveSuccesses = 0
+		st.LastError = err.Error()
+		if st.Healthy && st.ConsecutiveFailures >= cfg.FailureThreshold {
+			st.Healthy = false
+			return *st, true
+		}
+		return PoolHealth{}, false
+	}
+
+	st.ConsecutiveSuccesses++
+	st.ConsecutiveFailures = 0
+	st.LastError = ""
+	if !st.Healthy && st.ConsecutiveSuccesses >= cfg.SuccessThreshold {
+		st.Healthy = true
+		return *st, true
+	}
+	return PoolHealth{}, false
+}
+
+// IsHealthy reports whether a pool is considered up
+// Pools that have not been checked yet are healthy.
+func IsHealthy(pool *driver.PGPool) bool {
+	health.mu.RLock()
+	defer health.mu.RUnlock()
+	st, ok := health.status[pool]
+	return !ok || st.Healthy
+}
+
+// HealthReport returns the status of every registered pool, sorted by name
+func HealthReport() []PoolHealth {
+	targets := monitoredPools()
+
+	health.mu.RLock()
+	defer health.mu.RUnlock()
+
+	report := make([]PoolHealth, 0, len(targets))
+	for _, t := range targets {
+		if st, ok := health.status[t.pool]; ok {
+			report = append(report, *st)
+		} else {
+			report = append(report, PoolHealth{Name: t.name, Writable: t.writable, Healthy: true})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	return report
+}
+
+// IsReady reports whether every writable pool is healthy (suitable for readiness probes)
+func IsReady() bool {
+	for _, st := range HealthReport() {
+		if st.Writable && !st.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// resetHealth stops the monitor and forgets all statuses
+func resetHealth() {
+	StopHealthMonitor()
+	health.mu.Lock()
+	health.status = make(map[*driver.PGPool]*PoolHealth)
+	health.mu.Unlock()
+}
+
+// monitoredPool is a pool with the name it is reported under
+type monitoredPool struct {
+	name     string
+	pool     *driver.PGPool
+	writable bool
+}
+
+// monitoredPools lists every distinct registered pool
+func monitoredPools() []monitoredPool {
+	norm.mu.RLock()
+	defer norm.mu.RUnlock()
+
+	seen := make(map[*driver.PGPool]bool)
+	var targets []monitoredPool
+	add := func(name string, pool *driver.PGPool, writable bool) {
+		if pool == nil || seen[pool] {
+			return
+		}
+		seen[pool] = true
+		targets = append(targets, monitoredPool{name: name, pool: pool, writable: writable})
+	}
+
+	for name, pool := range norm.pools {
+		add(name, pool, name == "primary" || name == "write")
+	}
+	for shardName, shard := range norm.shards {
+		add(shardName+":primary", shard.primary, true)
+		for key, pool := range shard.standalone {
+			add(shardName+":standalone:"+key, pool, true)
+		}
+	}
+	return targets
+}
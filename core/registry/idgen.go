@@ -0,0 +1,234 @@
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IDGenerator produces globally unique primary keys in the application
+// Selected per field with a tag such as norm:"pk;idgen:snowflake".
+type IDGenerator interface {
+	// NextID returns a new key for a row of table that will be stored on shard
+	// (shard is "" when the target shard isn't known yet)
+	NextID(table, shard string) (interface{}, error)
+	// ColumnType is the Postgres column type holding the keys
+	ColumnType() string
+}
+
+// IDGenStride is the idgen name of per-shard offset/stride identities
+// The database generates the keys: shard i of n issues i+1, i+1+n, i+1+2n, ...
+const IDGenStride = "stride"
+
+// WorkerIDEnv names the environment variable holding the snowflake worker ID (0-31)
+const WorkerIDEnv = "NORM_WORKER_ID"
+
+var (
+	idGenerators   = map[string]IDGenerator{}
+	idGeneratorsMu sync.RWMutex
+
+	// snowflakeErr explains why the built-in snowflake generator isn't registered
+	snowflakeErr error
+)
+
+func init() {
+	idGenerators["ulid"] = ULIDGenerator{}
+	idGenerators["uuidv7"] = UUIDv7Generator{}
+
+	// Snowflake needs a worker ID unique among the processes writing to a shard
+	if gen, err := snowflakeFromEnv(); err != nil {
+		snowflakeErr = err
+	} else {
+		idGenerators["snowflake"] = gen
+	}
+}
+
+// snowflakeFromEnv builds the built-in snowflake generator from NORM_WORKER_ID
+func snowflakeFromEnv() (*Snowflake, error) {
+	env, ok := os.LookupEnv(WorkerIDEnv)
+	if !ok {
+		return nil, fmt.Errorf("no worker ID: set %s or register NewSnowflake(worker)", WorkerIDEnv)
+	}
+	worker, err := strconv.ParseInt(strings.TrimSpace(env), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s=%q is not a worker ID", WorkerIDEnv, env)
+	}
+	return NewSnowflake(worker)
+}
+
+// RegisterIDGenerator makes a generator available to idgen:<name> tags
+// Built-ins: "snowflake", "ulid", "uuidv7" and "stride" (database-side).
+func RegisterIDGenerator(name string, gen IDGenerator) {
+	idGeneratorsMu.Lock()
+	defer idGeneratorsMu.Unlock()
+	idGenerators[name] = gen
+}
+
+// GetIDGenerator returns the generator registered under name
+func GetIDGenerator(name string) (IDGenerator, bool) {
+	idGeneratorsMu.RLock()
+	defer idGeneratorsMu.RUnlock()
+	gen, ok := idGenerators[name]
+	return gen, ok
+}
+
+// missingIDGenerator explains why no generator is registered under name
+func missingIDGenerator(name string) error {
+	if name == "snowflake" && snowflakeErr != nil {
+		return fmt.Errorf("idgen:snowflake: %w", snowflakeErr)
+	}
+	return fmt.Errorf("unknown idgen: %s", name)
+}
+
+// ShardIndex returns the ordinal shard was registered with (see ShardBuilder.Ordinal)
+// It fails for unknown shards and shards registered without an ordinal.
+func ShardIndex(shard string) (int, error) {
+	norm.mu.RLock()
+	defer norm.mu.RUnlock()
+
+	pools, ok := norm.shards[shard]
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("unknown shard '%s'", shard)
+	case pools.ordinal < 0:
+		return 0, fmt.Errorf("shard '%s' has no ordinal (register it with Shard(\"%s\").Ordinal(n))", shard, shard)
+	}
+	return pools.ordinal, nil
+}
+
+// --- Snowflake ---
+
+// snowflakeEpoch is the zero time of snowflake timestamps (2024-01-01 UTC)
+const snowflakeEpoch = 1704067200000
+
+// Snowflake issues 63-bit keys: 41 bits of milliseconds, 5 bits of shard,
+// 5 bits of worker and a 12-bit sequence. Keys sort by creation time.
+type Snowflake struct {
+	worker int64
+
+	mu   sync.Mutex
+	last int64
+	seq  int64
+}
+
+// NewSnowflake returns a snowflake generator for a worker (0-31)
+// Processes writing to the same shard must use different workers.
+func NewSnowflake(worker int64) (*Snowflake, error) {
+	if worker < 0 || worker > 0x1f {
+		return nil, fmt.Errorf("snowflake worker ID %d out of range 0-31", worker)
+	}
+	return &Snowflake{worker: worker}, nil
+}
+
+// NextID returns the next snowflake key
+// In shard mode the target shard must be known, and at most 32 shards fit in the key.
+func (s *Snowflake) NextID(table, shard string) (interface{}, error) {
+	shardBits := int64(0)
+	if GetMode() == "shard" {
+		if shard == "" {
+			return nil, fmt.Errorf("snowflake: target shard of %s is unknown (set the shard key before inserting)", table)
+		}
+		idx, err := ShardIndex(shard)
+		if err != nil {
+			return nil, fmt.Errorf("snowflake: %w", err)
+		}
+		shardBits = int64(idx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli() - snowflakeEpoch
+	if now < s.last {
+		now = s.last // clock moved backwards: keep issuing from the last timestamp
+	}
+	if now == s.last {
+		s.seq = (s.seq + 1) & 0xfff
+		if s.seq == 0 {
+			// Sequence exhausted for this millisecond
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = now
+
+	return now<<22 | shardBits<<17 | s.worker<<12 | s.seq, nil
+}
+
+// ColumnType returns BIGINT
+func (s *Snowflake) ColumnType() string {
+	return "BIGINT"
+}
+
+// --- ULID ---
+
+// crockford is the ULID base32 alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator issues 26-character ULIDs (48-bit millisecond time + 80 random bits)
+type ULIDGenerator struct{}
+
+// NextID returns a new ULID string
+func (ULIDGenerator) NextID(table, shard string) (interface{}, error) {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, fmt.Errorf("ulid: %w", err)
+	}
+
+	// 128 bits as 26 base32 digits (the first digit holds the top 3 bits)
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+// ColumnType returns CHAR(26)
+func (ULIDGenerator) ColumnType() string {
+	return "CHAR(26)"
+}
+
+// --- UUIDv7 ---
+
+// UUIDv7Generator issues time-ordered RFC 9562 version 7 UUIDs
+type UUIDv7Generator struct{}
+
+// NextID returns a new UUIDv7 in its canonical string form
+func (UUIDv7Generator) NextID(table, shard string) (interface{}, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, fmt.Errorf("uuidv7: %w", err)
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// ColumnType returns UUID
+func (UUIDv7Generator) ColumnType() string {
+	return "UUID"
+}
@@ -0,0 +1,283 @@
+package registry
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ShardStrategy maps a shard key value to a shard name
+type ShardStrategy interface {
+	// ShardFor returns the shard that owns rows with the given key value
+	ShardFor(key interface{}) (string, error)
+	// Shards returns every shard the strategy can route to
+	Shards() []string
+}
+
+// ShardBy declares row-level sharding for the table
+// Rows are routed by the value of column using strategy, and the table is
+// registered (role: primary) on every shard the strategy can return.
+// Usage:
+//
+//	Table(Order{}, "orders").ShardBy("tenant_id", HashSharding("s1", "s2", "s3"))
+func (tm *TableModel) ShardBy(column string, strategy ShardStrategy) error {
+	if strategy == nil {
+		return fmt.Errorf("table %s: shard strategy is nil", tm.TableName)
+	}
+	if len(strategy.Shards()) == 0 {
+		return fmt.Errorf("table %s: shard strategy has no shards", tm.TableName)
+	}
+
+	found := false
+	for _, f := range tm.Fields {
+		if f.Fieldname == column {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("table %s: shard key column '%s' not found", tm.TableName, column)
+	}
+
+	tm.ShardKey = column
+	tm.Sharding = strategy
+
+	if tm.Roles == nil {
+		tm.Roles = make(map[string]map[string]struct{})
+	}
+	if tm.Roles["primary"] == nil {
+		tm.Roles["primary"] = make(map[string]struct{})
+	}
+	for _, shard := range strategy.Shards() {
+		tm.Roles["primary"][shard] = struct{}{}
+	}
+
+	fmt.Printf("  ✓ Table '%s' sharded by '%s' across %v\n", tm.TableName, column, strategy.Shards())
+	return nil
+}
+
+// IsKeySharded returns true if rows of this table are spread across shards by a shard key
+func (tm *TableModel) IsKeySharded() bool {
+	return tm.Sharding != nil && tm.ShardKey != ""
+}
+
+// derefKey unwraps pointer key values (nil pointers stay nil)
+func derefKey(key interface{}) interface{} {
+	v := reflect.ValueOf(key)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// --- Hash ---
+
+// HashStrategy distributes keys evenly with FNV-1a over the key's string form
+// so int, int64 and uint keys with the same value land on the same shard.
+type HashStrategy struct {
+	shards []string
+}
+
+// HashSharding creates a hash strategy over the given shards (order matters)
+func HashSharding(shards ...string) *HashStrategy {
+	return &HashStrategy{shards: shards}
+}
+
+func (h *HashStrategy) ShardFor(key interface{}) (string, error) {
+	key = derefKey(key)
+	if key == nil {
+		return "", fmt.Errorf("shard key is nil")
+	}
+	if len(h.shards) == 0 {
+		return "", fmt.Errorf("hash sharding has no shards")
+	}
+	hasher := fnv.New64a()
+	hasher.Write([]byte(fmt.Sprint(key)))
+	return h.shards[hasher.Sum64()%uint64(len(h.shards))], nil
+}
+
+func (h *HashStrategy) Shards() []string {
+	return append([]string(nil), h.shards...)
+}
+
+// --- Range ---
+
+// ShardRange assigns keys in [Min, Max) to Shard
+// A nil Min or Max means unbounded. Bounds may be numbers or strings.
+type ShardRange struct {
+	Shard string
+	Min   interface{}
+	Max   interface{}
+}
+
+// RangeStrategy routes keys by the range they fall into
+type RangeStrategy struct {
+	ranges []ShardRange
+}
+
+// RangeSharding creates a range strategy
+// Usage:
+//
+//	RangeSharding(
+//	    ShardRange{Shard: "s1", Max: 1000},
+//	    ShardRange{Shard: "s2", Min: 1000},
+//	)
+func RangeSharding(ranges ...ShardRange) *RangeStrategy {
+	return &RangeStrategy{ranges: ranges}
+}
+
+func (r *RangeStrategy) ShardFor(key interface{}) (string, error) {
+	key = derefKey(key)
+	if key == nil {
+		return "", fmt.Errorf("shard key is nil")
+	}
+	for _, rg := range r.ranges {
+		if rg.Min != nil {
+			c, err := compareKeys(key, rg.Min)
+			if err != nil {
+				return "", err
+			}
+			if c < 0 {
+				continue
+			}
+		}
+		if rg.Max != nil {
+			c, err := compareKeys(key, rg.Max)
+			if err != nil {
+				return "", err
+			}
+			if c >= 0 {
+				continue
+			}
+		}
+		return rg.Shard, nil
+	}
+	return "", fmt.Errorf("no shard range contains key %v", key)
+}
+
+func (r *RangeStrategy) Shards() []string {
+	seen := make(map[string]bool)
+	var shards []string
+	for _, rg := range r.ranges {
+		if !seen[rg.Shard] {
+			seen[rg.Shard] = true
+			shards = append(shards, rg.Shard)
+		}
+	}
+	return shards
+}
+
+// compareKeys compares two numeric or string keys (-1, 0, 1)
+func compareKeys(a, b interface{}) (int, error) {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	if av.Kind() == reflect.String && bv.Kind() == reflect.String {
+		switch {
+		case av.String() < bv.String():
+			return -1, nil
+		case av.String() > bv.String():
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	af, aok := toFloat(av)
+	bf, bok := toFloat(bv)
+	if !aok || !bok {
+		return 0, fmt.Errorf("cannot compare shard key %v (%T) with range bound %v (%T)", a, a, b, b)
+	}
+	switch {
+	case af < bf:
+		return -1, nil
+	case af > bf:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// --- Lookup ---
+
+// LookupStrategy routes keys through an explicit key => shard table
+// Keys are matched by their string form. Unknown keys go to the default
+// shard, if one is set. Entries can be changed at runtime with Set.
+type LookupStrategy struct {
+	mu           sync.RWMutex
+	table        map[string]string
+	defaultShard string
+}
+
+// LookupSharding creates a lookup-table strategy
+// Usage:
+//
+//	LookupSharding(map[string]string{"acme": "s1", "globex": "s2"}, "s3") // s3 = default
+func LookupSharding(table map[string]string, defaultShard ...string) *LookupStrategy {
+	l := &LookupStrategy{table: make(map[string]string, len(table))}
+	for k, v := range table {
+		l.table[k] = v
+	}
+	if len(defaultShard) > 0 {
+		l.defaultShard = defaultShard[0]
+	}
+	return l
+}
+
+// Set assigns a key to a shard
+func (l *LookupStrategy) Set(key interface{}, shard string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.table[fmt.Sprint(derefKey(key))] = shard
+}
+
+func (l *LookupStrategy) ShardFor(key interface{}) (string, error) {
+	key = derefKey(key)
+	if key == nil {
+		return "", fmt.Errorf("shard key is nil")
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if shard, ok := l.table[fmt.Sprint(key)]; ok {
+		return shard, nil
+	}
+	if l.defaultShard != "" {
+		return l.defaultShard, nil
+	}
+	return "", fmt.Errorf("no shard mapped for key %v", key)
+}
+
+func (l *LookupStrategy) Shards() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	seen := make(map[string]bool)
+	var shards []string
+	for _, shard := range l.table {
+		if !seen[shard] {
+			seen[shard] = true
+			shards = append(shards, shard)
+		}
+	}
+	if l.defaultShard != "" && !seen[l.defaultShard] {
+		shards = append(shards, l.defaultShard)
+	}
+	sort.Strings(shards)
+	return shards
+}
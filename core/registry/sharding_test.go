@@ -0,0 +1,127 @@
+package registry
+
+import "testing"
+
+func TestHashShardingShardFor(t *testing.T) {
+	h := HashSharding("s1", "s2", "s3")
+
+	first, err := h.ShardFor(12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if got, _ := h.ShardFor(12345); got != first {
+			t.Fatalf("ShardFor(12345) = %q, then %q", first, got)
+		}
+	}
+
+	n := int64(12345)
+	for _, key := range []interface{}{int64(12345), uint(12345), &n} {
+		if got, err := h.ShardFor(key); err != nil || got != first {
+			t.Errorf("ShardFor(%T) = %q, %v; want %q", key, got, err, first)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		s, _ := h.ShardFor(i)
+		seen[s] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("100 keys landed on %d of 3 shards", len(seen))
+	}
+
+	if _, err := h.ShardFor(nil); err == nil {
+		t.Error("ShardFor(nil) succeeded")
+	}
+	var np *int
+	if _, err := h.ShardFor(np); err == nil {
+		t.Error("ShardFor(nil pointer) succeeded")
+	}
+	if _, err := HashSharding().ShardFor(1); err == nil {
+		t.Error("ShardFor without shards succeeded")
+	}
+}
+
+func TestRangeShardingShardFor(t *testing.T) {
+	r := RangeSharding(
+		ShardRange{Shard: "s1", Max: 1000},
+		ShardRange{Shard: "s2", Min: 1000, Max: 2000},
+		ShardRange{Shard: "s3", Min: 2000},
+	)
+	tests := []struct {
+		key  interface{}
+		want string
+	}{
+		{-5, "s1"},
+		{999, "s1"},
+		{1000, "s2"},
+		{int64(1999), "s2"},
+		{1999.5, "s2"},
+		{2000, "s3"},
+		{uint64(1 << 40), "s3"},
+	}
+	for _, tt := range tests {
+		got, err := r.ShardFor(tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("ShardFor(%v) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+
+	bounded := RangeSharding(ShardRange{Shard: "s1", Min: 0, Max: 10})
+	if _, err := bounded.ShardFor(10); err == nil {
+		t.Error("ShardFor outside every range succeeded")
+	}
+	if _, err := bounded.ShardFor("5"); err == nil {
+		t.Error("ShardFor with a string key on numeric bounds succeeded")
+	}
+
+	letters := RangeSharding(ShardRange{Shard: "a-m", Max: "n"}, ShardRange{Shard: "n-z", Min: "n"})
+	if got, _ := letters.ShardFor("globex"); got != "a-m" {
+		t.Errorf("ShardFor(globex) = %q; want a-m", got)
+	}
+	if got, _ := letters.ShardFor("nexus"); got != "n-z" {
+		t.Errorf("ShardFor(nexus) = %q; want n-z", got)
+	}
+
+	if got := r.Shards(); len(got) != 3 {
+		t.Errorf("Shards() = %v", got)
+	}
+}
+
+func TestLookupShardingShardFor(t *testing.T) {
+	l := LookupSharding(map[string]string{"acme": "s1", "42": "s2"})
+
+	key := "acme"
+	tests := []struct {
+		key  interface{}
+		want string
+	}{
+		{"acme", "s1"},
+		{&key, "s1"},
+		{42, "s2"},
+		{int64(42), "s2"},
+	}
+	for _, tt := range tests {
+		got, err := l.ShardFor(tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("ShardFor(%v) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+
+	if _, err := l.ShardFor("globex"); err == nil {
+		t.Error("ShardFor of an unknown key without a default succeeded")
+	}
+	l.Set("globex", "s3")
+	if got, err := l.ShardFor("globex"); err != nil || got != "s3" {
+		t.Errorf("ShardFor(globex) after Set = %q, %v; want s3", got, err)
+	}
+
+	withDefault := LookupSharding(map[string]string{"acme": "s1"}, "s9")
+	if got, err := withDefault.ShardFor("initech"); err != nil || got != "s9" {
+		t.Errorf("ShardFor(initech) = %q, %v; want default s9", got, err)
+	}
+	if _, err := withDefault.ShardFor(nil); err == nil {
+		t.Error("ShardFor(nil) succeeded")
+	}
+}
@@ -28,6 +28,10 @@ type TableModel struct {
 
 	// Original model struct (used for migration reflection)
 	Model interface{}
+
+	// Row-level sharding (see ShardBy); empty for whole-table placement
+	ShardKey string
+	Sharding ShardStrategy
 }
 
 // TableBuilder for fluent API
@@ -45,6 +49,7 @@ type Field struct{
 	Max string
 	NotNull bool
 	Default string
+	IDGen string // key generator name from idgen:<name> ("" = none)
 }
 // Table registers a table with the ORM for migrations and routing
 // Usage:
@@ -78,6 +83,7 @@ func Table(model interface{}, tableName ...string) *TableModel {
 	defer tableReg.mu.Unlock()
 
 	table := registerTable(model, name)
+	table.TableName = name
 
 	// initialize roles
 	table.Roles = make(map[string]map[string]struct{})
@@ -110,19 +116,12 @@ func registerTable(model interface{}, tableName string) TableModel {
 	table.Model = model
 	table.Fields = make([]Field, 0, t.NumField())
 
-	for i := 0; i < t.NumField(); i++ {
-		sf := t.Field(i)
-
-		// skip unexported fields
-		if sf.PkgPath != "" {
-			continue
-		}
-
-		normTag := sf.Tag.Get("norm")
-		tags := utils.ParseNormTags(normTag)
+	// unexported fields and relations (filled by Preload, not stored) are not in the plan
+	for _, fp := range utils.PlanOf(t).Fields {
+		tags := fp.Tags
 		f := Field{
-			Fieldname: utils.ResolveColumnName(sf),
-			Fieldtype: utils.GetPostgresType(sf),
+			Fieldname: fp.Column,
+			Fieldtype: utils.GetPostgresType(fp.Field),
 		}
 
 		if _, ok := tags["index"]; ok {
@@ -136,6 +135,21 @@ func registerTable(model interface{}, tableName string) TableModel {
 			f.Serial = true
 		}
 	
+		if gen, ok := tags["idgen"]; ok {
+			f.IDGen = gen.(string)
+			if f.IDGen == IDGenStride {
+				f.Serial = true
+			} else if g, ok := GetIDGenerator(f.IDGen); ok {
+				f.Serial = false // keys come from the application, not an identity
+				if _, explicit := tags["type"]; !explicit {
+					f.Fieldtype = g.ColumnType()
+				}
+			} else {
+				fmt.Println("error while registering table", tableName, "at col", f.Fieldname)
+				panic(missingIDGenerator(f.IDGen).Error())
+			}
+		}
+
 		if _, ok := tags["unique"]; ok {
 			f.Unique = true
 		}
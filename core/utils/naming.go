@@ -48,4 +48,11 @@ func ResolveColumnName(sf reflect.StructField) string {
 		}
 	}
 	return column
-}
\ No newline at end of file
+}
+// IsRelationField reports whether a struct field holds related records
+// (norm:"rel") rather than a column. Relation fields are filled by Preload
+// and skipped by registration, migration, inserts and updates.
+func IsRelationField(sf reflect.StructField) bool {
+	_, ok := ParseNormTags(sf.Tag.Get("norm"))["rel"]
+	return ok
+}
@@ -40,6 +40,11 @@ func GetPostgresType(field reflect.StructField) string {
 		return sqlType.(string)
 	}
 
+	// Registered and built-in type mappings (see RegisterType)
+	if m, ok := LookupType(field.Type); ok && m.DDL != "" {
+		return m.DDL
+	}
+
 	fieldType := field.Type
 	if fieldType.Kind() == reflect.Ptr {
 		fieldType = fieldType.Elem()
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FieldPlan is the precomputed column mapping of one struct field
+type FieldPlan struct {
+	Field     reflect.StructField
+	Index     []int                                      // index path for reflect.Value.FieldByIndex
+	Column    string                                     // resolved column name (with any embed prefix)
+	Tags      map[string]interface{}                     // parsed norm tag (shared, do not modify)
+	Elem      reflect.Type                               // field type with one pointer level removed
+	Nullable  bool                                       // pointer field: NULL leaves it nil
+	InPointer bool                                       // reached through a struct pointer (*BaseModel), which stays nil when its columns are NULL
+	Decode    func(src interface{}) (interface{}, error) // decoder of a registered type pgx can't scan (nil = scanned directly)
+	depth     int                                        // embedding depth; shallower fields win column clashes
+}
+
+// TypePlan is the column mapping of a struct type, computed once per type
+// It is shared by the scanners, the query builder and table registration so
+// norm tags are parsed once instead of on every query. Anonymous embedded
+// structs and fields tagged norm:"embed" are flattened into Fields.
+type TypePlan struct {
+	Type      reflect.Type
+	Fields    []*FieldPlan // exported column fields in declaration order
+	Relations []*FieldPlan // norm:"rel" fields (filled by Preload)
+	Nested    []*FieldPlan // named struct fields a joined table can be scanned into
+	byColumn  map[string]*FieldPlan
+	qualified sync.Map // "ref.column" -> *FieldPlan resolved by NestedColumn (nil when unmapped)
+	gen       uint64   // typeGen the plan was built at
+}
+
+var (
+	typePlans   sync.Map      // reflect.Type -> *TypePlan
+	typeGen     atomic.Uint64 // bumped by RegisterType; plans of older generations are rebuilt
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// PlanOf returns the cached plan of a struct type (pointers are dereferenced)
+// It returns nil when t is not a struct.
+func PlanOf(t reflect.Type) *TypePlan {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	gen := typeGen.Load()
+	if p, ok := typePlans.Load(t); ok && p.(*TypePlan).gen == gen {
+		return p.(*TypePlan)
+	}
+
+	p := &TypePlan{Type: t, byColumn: make(map[string]*FieldPlan), gen: gen}
+	var fields []*FieldPlan
+	p.collect(t, nil, "", 0, false, map[reflect.Type]bool{t: true}, &fields)
+
+	// Like Go's field promotion, the shallowest field wins a column clash
+	for _, f := range fields {
+		if prev, ok := p.byColumn[f.Column]; !ok || f.depth < prev.depth {
+			p.byColumn[f.Column] = f
+		}
+	}
+	for _, f := range fields {
+		if p.byColumn[f.Column] == f {
+			p.Fields = append(p.Fields, f)
+		}
+	}
+
+	actual, loaded := typePlans.LoadOrStore(t, p)
+	if !loaded {
+		return p
+	}
+	if a := actual.(*TypePlan); a.gen == gen {
+		return a
+	}
+	typePlans.CompareAndSwap(t, actual, p) // replace a plan of an older generation
+	return p
+}
+
+// collect adds the fields of t (reached through index) to the plan
+func (p *TypePlan) collect(t reflect.Type, index []int, prefix string, depth int, inPtr bool, visiting map[reflect.Type]bool, fields *[]*FieldPlan) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tags := ParseNormTags(sf.Tag.Get("norm"))
+		path := append(append([]int{}, index...), i)
+
+		st := sf.Type
+		if st.Kind() == reflect.Ptr {
+			st = st.Elem()
+		}
+		_, embed := tags["embed"]
+		if (sf.Anonymous || embed) && st.Kind() == reflect.Struct && st != timeType && !visiting[st] {
+			// Promoted fields of an unexported embedded struct are reachable,
+			// but an unexported pointer can't be allocated when scanning
+			if sf.PkgPath != "" && (!sf.Anonymous || sf.Type.Kind() == reflect.Ptr) {
+				continue
+			}
+			pre := prefix
+			if s, ok := tags["prefix"].(string); ok {
+				pre += s
+			}
+			visiting[st] = true
+			p.collect(st, path, pre, depth+1, inPtr || sf.Type.Kind() == reflect.Ptr, visiting, fields)
+			delete(visiting, st)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := &FieldPlan{
+			Field:     sf,
+			Index:     path,
+			Column:    prefix + ResolveColumnName(sf),
+			Tags:      tags,
+			Elem:      sf.Type,
+			InPointer: inPtr,
+			depth:     depth,
+		}
+		if sf.Type.Kind() == reflect.Ptr {
+			f.Elem = sf.Type.Elem()
+			f.Nullable = true
+		}
+		if m, ok := LookupType(f.Elem); ok {
+			f.Decode = m.ScanDecode()
+		}
+		if _, ok := tags["rel"]; ok {
+			p.Relations = append(p.Relations, f)
+			continue
+		}
+		*fields = append(*fields, f)
+		if depth == 0 && isNestable(f.Elem) {
+			p.Nested = append(p.Nested, f)
+		}
+	}
+}
+
+// isNestable reports whether a struct field can hold the columns of a joined
+// table; time.Time, sql.Scanner and registered types are single values
+func isNestable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType) {
+		return false
+	}
+	_, registered := LookupType(t)
+	return !registered
+}
+
+// Column returns the field mapped to a column (nil when none is)
+func (p *TypePlan) Column(name string) *FieldPlan {
+	return p.byColumn[name]
+}
+
+// NestedColumn returns the field of a qualified column ("users.email"),
+// calling resolve the first time the column is seen
+// The result is cached with the plan, so it is dropped when RegisterType
+// invalidates the plans.
+func (p *TypePlan) NestedColumn(name string, resolve func() *FieldPlan) *FieldPlan {
+	if f, ok := p.qualified.Load(name); ok {
+		return f.(*FieldPlan)
+	}
+	f, _ := p.qualified.LoadOrStore(name, resolve())
+	return f.(*FieldPlan)
+}
+
+// Get returns the field of struct value v; ok is false when an embedded
+// pointer on the way is nil
+func (f *FieldPlan) Get(v reflect.Value) (reflect.Value, bool) {
+	fv, err := v.FieldByIndexErr(f.Index)
+	return fv, err == nil
+}
+
+// Ensure returns the field of addressable struct value v, allocating nil
+// embedded pointers on the way
+// It fails when a nil embedded pointer can't be set because v was passed by value.
+func (f *FieldPlan) Ensure(v reflect.Value) (reflect.Value, error) {
+	for i, x := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("field %s is behind a nil embedded %s: pass a pointer to the struct", f.Field.Name, v.Type())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TypeMapping describes how values of a Go type are stored in Postgres
+// Encode turns a field value or query argument into a value pgx can send;
+// Decode turns the value pgx reads for the column (what rows.Values returns,
+// e.g. pgtype.Numeric for NUMERIC) into the registered type. Either may be nil
+// when pgx handles the type itself, e.g. through driver.Valuer / sql.Scanner.
+type TypeMapping struct {
+	DDL    string                                     // column type created by migrations, e.g. "NUMERIC(20,4)"
+	Encode func(v interface{}) (interface{}, error)   // Go value => query argument
+	Decode func(src interface{}) (interface{}, error) // column value => value of the registered type
+
+	native bool // pgx scans the type itself; Decode only converts row map values
+}
+
+var (
+	typeMappings   = map[reflect.Type]TypeMapping{}
+	typeMappingsMu sync.RWMutex
+	uuidType       = reflect.TypeOf([16]byte{})
+)
+
+func init() {
+	typeMappings[reflect.TypeOf(time.Duration(0))] = TypeMapping{DDL: "INTERVAL", Decode: decodeDuration, native: true}
+	typeMappings[uuidType] = TypeMapping{DDL: "UUID", Decode: decodeUUID, native: true}
+	typeMappings[reflect.TypeOf(net.IP{})] = TypeMapping{DDL: "INET", Decode: decodeIP, native: true}
+	typeMappings[reflect.TypeOf(json.RawMessage{})] = TypeMapping{DDL: "JSONB", Decode: decodeRawJSON, native: true}
+}
+
+// RegisterType maps the Go type of sample to a Postgres column type and codec
+// Registered types are used by migrations, encoded in query arguments and
+// decoded by the scanners. Built-ins: time.Duration (INTERVAL; like pgx, a
+// month is read back as 30 days), [16]byte and other 16-byte arrays such as
+// uuid.UUID (UUID), net.IP (INET) and json.RawMessage (JSONB); registering one
+// of them replaces it. Cached type plans are dropped so later scans use the
+// new mapping; tables registered before keep their column types.
+func RegisterType(sample interface{}, m TypeMapping) {
+	t := reflect.TypeOf(sample)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		panic("RegisterType needs a typed sample value")
+	}
+	m.native = false
+
+	typeMappingsMu.Lock()
+	typeMappings[t] = m
+	typeMappingsMu.Unlock()
+
+	// Plans hold decoders and nestable fields computed from the mappings
+	typeGen.Add(1)
+	typePlans.Clear()
+}
+
+// LookupType returns the mapping of t (pointers are dereferenced)
+// 16-byte arrays without a mapping of their own use the UUID mapping of [16]byte.
+func LookupType(t reflect.Type) (TypeMapping, bool) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return TypeMapping{}, false
+	}
+
+	typeMappingsMu.RLock()
+	defer typeMappingsMu.RUnlock()
+	if m, ok := typeMappings[t]; ok {
+		return m, true
+	}
+	if t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8 {
+		m, ok := typeMappings[uuidType]
+		return m, ok
+	}
+	return TypeMapping{}, false
+}
+
+// ScanDecode returns the decoder for values scanned straight from pgx rows
+// It is nil for the built-in mappings, whose types pgx scans itself.
+func (m TypeMapping) ScanDecode() func(src interface{}) (interface{}, error) {
+	if m.native {
+		return nil
+	}
+	return m.Decode
+}
+
+// decodeDuration reads INTERVAL, BIGINT nanoseconds or a Go duration string
+// A month of an INTERVAL counts 30 days, as when pgx scans into time.Duration:
+// a Duration has no calendar months.
+func decodeDuration(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case pgtype.Interval:
+		d := time.Duration(v.Microseconds) * time.Microsecond
+		d += time.Duration(v.Days) * 24 * time.Hour
+		d += time.Duration(v.Months) * 30 * 24 * time.Hour
+		return d, nil
+	case int64:
+		return time.Duration(v), nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return nil, fmt.Errorf("cannot decode %T as time.Duration", src)
+}
+
+// decodeUUID reads a UUID from its 16 bytes or its text form
+func decodeUUID(src interface{}) (interface{}, error) {
+	var out [16]byte
+	switch v := src.(type) {
+	case [16]byte:
+		return v, nil
+	case []byte:
+		if len(v) == 16 {
+			copy(out[:], v)
+			return out, nil
+		}
+		src = string(v)
+	}
+	if s, ok := src.(string); ok {
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil || len(b) != 16 {
+			return nil, fmt.Errorf("invalid UUID %q", s)
+		}
+		copy(out[:], b)
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot decode %T as UUID", src)
+}
+
+// decodeIP reads an INET address (the network mask is dropped)
+func decodeIP(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case netip.Prefix:
+		return net.IP(v.Addr().AsSlice()), nil
+	case netip.Addr:
+		return net.IP(v.AsSlice()), nil
+	case string:
+		addr, _, _ := strings.Cut(v, "/")
+		if ip := net.ParseIP(addr); ip != nil {
+			return ip, nil
+		}
+		return nil, fmt.Errorf("invalid IP address %q", v)
+	}
+	return nil, fmt.Errorf("cannot decode %T as net.IP", src)
+}
+
+// decodeRawJSON keeps JSON text as is and re-encodes decoded JSON values
+func decodeRawJSON(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case []byte:
+		return json.RawMessage(append([]byte(nil), v...)), nil
+	case string:
+		return json.RawMessage(v), nil
+	}
+	b, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(b), nil
+}
@@ -1,15 +1,18 @@
 package norm
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/skssmd/norm/core/driver"
 	"github.com/skssmd/norm/core/engine"
 	"github.com/skssmd/norm/core/migration"
 	"github.com/skssmd/norm/core/registry"
+	"github.com/skssmd/norm/core/utils"
 )
 
 var autoMigrator *migration.AutoMigrator
@@ -30,6 +33,83 @@ func Reset() {
 	registry.Reset()
 }
 
+// ============================================================
+// Load Balancing
+// ============================================================
+
+// Balancer picks one pool among the registered replica or read pools
+type Balancer = registry.Balancer
+
+// SetBalancer sets the strategy used to balance SELECTs across replica/read pools
+// Usage:
+//
+//	norm.Register(dsn1).Weight(3).Replica()
+//	norm.Register(dsn2).Replica()
+//	norm.SetBalancer(norm.Weighted())
+func SetBalancer(b Balancer) {
+	registry.SetBalancer(b)
+}
+
+// RoundRobin cycles through pools in registration order (default)
+func RoundRobin() Balancer {
+	return registry.NewRoundRobinBalancer()
+}
+
+// RandomBalance picks a random pool for every query
+func RandomBalance() Balancer {
+	return registry.NewRandomBalancer()
+}
+
+// LeastConn picks the pool with the fewest acquired connections
+func LeastConn() Balancer {
+	return registry.NewLeastConnBalancer()
+}
+
+// Weighted distributes queries proportionally to the pool weights set with Weight()
+func Weighted() Balancer {
+	return registry.NewWeightedBalancer()
+}
+
+// ============================================================
+// Health Monitoring
+// ============================================================
+
+// HealthConfig configures the background pool health monitor
+type HealthConfig = registry.HealthConfig
+
+// PoolHealth is the health status of one connection pool
+type PoolHealth = registry.PoolHealth
+
+var (
+	// ErrNoWritablePool is returned for writes when the primary/write pool is down
+	ErrNoWritablePool = registry.ErrNoWritablePool
+	// ErrNoReadablePool is returned for reads when every candidate pool is down
+	ErrNoReadablePool = registry.ErrNoReadablePool
+)
+
+// StartHealthMonitor pings all pools periodically and routes around unhealthy ones
+// Usage:
+//
+//	norm.StartHealthMonitor(norm.HealthConfig{Interval: 5 * time.Second, FailureThreshold: 3})
+func StartHealthMonitor(cfg HealthConfig) {
+	registry.StartHealthMonitor(cfg)
+}
+
+// StopHealthMonitor stops the background health monitor
+func StopHealthMonitor() {
+	registry.StopHealthMonitor()
+}
+
+// HealthStatus returns the current health of every registered pool
+func HealthStatus() []PoolHealth {
+	return registry.HealthReport()
+}
+
+// IsReady reports whether all writable pools are healthy (for readiness probes)
+func IsReady() bool {
+	return registry.IsReady()
+}
+
 // ============================================================
 // Caching Registration
 // ============================================================
@@ -67,6 +147,138 @@ func RegisterTable(model interface{}, tableName ...string) *registry.TableModel
 	return registry.Table(model, tableName...)
 }
 
+// ShardStrategy maps a shard key value to a shard name (see TableModel.ShardBy)
+type ShardStrategy = registry.ShardStrategy
+
+// ShardRange assigns shard keys in [Min, Max) to a shard (nil bound = unbounded)
+type ShardRange = registry.ShardRange
+
+// ErrShardKeyRequired is returned when a query on a key-sharded table has no shard key value
+var ErrShardKeyRequired = engine.ErrShardKeyRequired
+
+// ScatterConfig controls fan-out of queries over tables that span several shards
+type ScatterConfig = engine.ScatterConfig
+
+// ScatterError lists the shards that failed during a fan-out query
+type ScatterError = engine.ScatterError
+
+// ErrAggregateNotMergeable is returned when a multi-shard aggregate can't be combined in the app
+var ErrAggregateNotMergeable = engine.ErrAggregateNotMergeable
+
+// SetScatterConfig sets the concurrency cap and partial-failure policy for fan-out queries
+// Usage:
+//
+//	norm.SetScatterConfig(norm.ScatterConfig{MaxConcurrency: 4, AllowPartial: true})
+func SetScatterConfig(cfg ScatterConfig) {
+	engine.SetScatterConfig(cfg)
+}
+
+// BulkConfig controls when BulkInsert switches to COPY and how it splits large inputs
+type BulkConfig = engine.BulkConfig
+
+// SetBulkConfig sets the COPY threshold and chunk size for bulk inserts
+// Usage:
+//
+//	norm.SetBulkConfig(norm.BulkConfig{CopyThreshold: 1000, ChunkSize: 50000})
+func SetBulkConfig(cfg BulkConfig) {
+	engine.SetBulkConfig(cfg)
+}
+
+// CascadeConfig controls application-level ondelete handling for soft keys (skey)
+type CascadeConfig = engine.CascadeConfig
+
+// CascadeReport lists the rows affected per table and shard by a cascading delete
+type CascadeReport = engine.CascadeReport
+
+// CascadeResult is one table/shard entry of a CascadeReport
+type CascadeResult = engine.CascadeResult
+
+// RestrictError reports a delete refused by an ondelete:restrict soft key
+type RestrictError = engine.RestrictError
+
+// ErrRestrictViolation matches any *RestrictError (errors.Is)
+var ErrRestrictViolation = engine.ErrRestrictViolation
+
+// DanglingSoftKeyError lists soft-key values written without a referenced row
+type DanglingSoftKeyError = engine.DanglingSoftKeyError
+
+// ErrDanglingSoftKey matches any *DanglingSoftKeyError (errors.Is)
+var ErrDanglingSoftKey = engine.ErrDanglingSoftKey
+
+// SetSoftKeyValidation checks skey values on every Insert, BulkInsert and Update when enabled
+// Usage:
+//
+//	norm.SetSoftKeyValidation(true)
+func SetSoftKeyValidation(enabled bool) {
+	engine.SetSoftKeyValidation(enabled)
+}
+
+// SetCascadeConfig sets the batch size for soft-key cascades, or disables them
+// Usage:
+//
+//	norm.SetCascadeConfig(norm.CascadeConfig{BatchSize: 500})
+func SetCascadeConfig(cfg CascadeConfig) {
+	engine.SetCascadeConfig(cfg)
+}
+
+// IDGenerator produces globally unique keys for idgen:<name> fields
+type IDGenerator = registry.IDGenerator
+
+// RegisterIDGenerator makes a key generator available to idgen:<name> tags
+// Register before the tables that use it.
+// Usage:
+//
+//	norm.RegisterIDGenerator("ids", myGenerator)
+func RegisterIDGenerator(name string, gen IDGenerator) {
+	registry.RegisterIDGenerator(name, gen)
+}
+
+// TypeMapping describes how a Go type is stored in Postgres: its column type and codec
+type TypeMapping = utils.TypeMapping
+
+// RegisterType maps a Go type to a Postgres column type with optional encode/decode functions
+// The mapping is used by migrations, query arguments and both scanners.
+// Register before the tables that use it.
+// Usage:
+//
+//	norm.RegisterType(decimal.Decimal{}, norm.TypeMapping{
+//	    DDL:    "NUMERIC(20,4)",
+//	    Decode: func(src any) (any, error) { return decimal.NewFromString(fmt.Sprint(src)) },
+//	})
+func RegisterType(sample interface{}, m TypeMapping) {
+	utils.RegisterType(sample, m)
+}
+
+// NewSnowflake returns a snowflake key generator for a worker (0-31)
+// The built-in "snowflake" generator takes its worker from NORM_WORKER_ID;
+// without it, register one before the tables that use idgen:snowflake.
+// Usage:
+//
+//	gen, err := norm.NewSnowflake(cfg.WorkerID)
+//	if err != nil { ... }
+//	norm.RegisterIDGenerator("snowflake", gen)
+func NewSnowflake(worker int64) (*registry.Snowflake, error) {
+	return registry.NewSnowflake(worker)
+}
+
+// HashSharding spreads rows evenly across shards by hashing the shard key
+// Usage:
+//
+//	norm.RegisterTable(Order{}, "orders").ShardBy("tenant_id", norm.HashSharding("s1", "s2", "s3"))
+func HashSharding(shards ...string) *registry.HashStrategy {
+	return registry.HashSharding(shards...)
+}
+
+// RangeSharding routes rows by the range their shard key falls into
+func RangeSharding(ranges ...ShardRange) *registry.RangeStrategy {
+	return registry.RangeSharding(ranges...)
+}
+
+// LookupSharding routes rows through an explicit key => shard table with an optional default shard
+func LookupSharding(table map[string]string, defaultShard ...string) *registry.LookupStrategy {
+	return registry.LookupSharding(table, defaultShard...)
+}
+
 // ============================================================
 // Query Builder Functions
 // ============================================================
@@ -99,6 +311,54 @@ func Model[T any](model T) *engine.Query[T] {
 	return q.From(model)
 }
 
+// Batch queues queries and sends them with one round trip per pool
+// Usage:
+//
+//	results, err := norm.Batch().
+//	    Add(norm.Table("users").Select().Where("id = $1", id), &user).
+//	    Add(norm.Table("orders").Select().Where("user_id = $1", id)).
+//	    Send(ctx)
+//	results[1].Scan(&orders)
+func Batch() *engine.Batch {
+	return engine.NewBatch()
+}
+
+// BatchResult is the outcome of one query of a Batch
+type BatchResult = engine.BatchResult
+
+// ScanMap returns a destination for All that fills *map[K]V keyed by a column
+// Usage:
+//
+//	byID := map[int64]User{}
+//	err := norm.Table("users").Select().All(ctx, norm.ScanMap("id", &byID))
+func ScanMap(key string, dest interface{}) *engine.KeyedMap {
+	return engine.ScanMap(key, dest)
+}
+
+// ErrNoRows is returned by First and other single-row reads when no row matches
+// (it is pgx.ErrNoRows, whether the table is on one pool or fanned out across shards)
+var ErrNoRows = engine.ErrNoRows
+
+// ScanConfig controls how result rows are mapped onto destinations
+type ScanConfig = engine.ScanConfig
+
+// ScanError reports a result value that could not be stored in its destination
+type ScanError = engine.ScanError
+
+// ErrUnmappedColumn is reported by strict scans for a result column without a destination field
+var ErrUnmappedColumn = engine.ErrUnmappedColumn
+
+// ErrUnfilledField is reported by strict scans for a destination field without a result column
+var ErrUnfilledField = engine.ErrUnfilledField
+
+// SetScanConfig sets the global scan configuration
+// Usage:
+//
+//	norm.SetScanConfig(norm.ScanConfig{Strict: true})
+func SetScanConfig(cfg ScanConfig) {
+	engine.SetScanConfig(cfg)
+}
+
 // BulkInsert creates a bulk insert builder from model
 // Usage:
 //
@@ -138,6 +398,150 @@ func Join(table1, table2 string) *engine.Query[any] {
 	return q.Join(table1, table2)
 }
 
+// ============================================================
+// WHERE Conditions
+// ============================================================
+
+// Cond is a composable WHERE condition accepted by Where, OrWhere and WhereNot
+// Placeholders are numbered automatically.
+// Usage:
+//
+//	norm.Table("users").Select().
+//	    Where(norm.Eq("status", "active")).
+//	    Where(norm.Or(norm.In("role", roles), norm.IsNull("deleted_at"))).
+//	    All(ctx, &users)
+type Cond = engine.Cond
+
+// P holds named parameters for Where, Having, Clause and Raw (referenced as :name)
+// Usage:
+//
+//	norm.Table("users").Select().Where("email = :email", norm.P{"email": e}).First(ctx, &user)
+type P = engine.P
+
+// Eq matches column = value (nil matches IS NULL)
+func Eq(column string, value interface{}) Cond { return engine.Eq(column, value) }
+
+// Ne matches column <> value (nil matches IS NOT NULL)
+func Ne(column string, value interface{}) Cond { return engine.Ne(column, value) }
+
+// Gt matches column > value
+func Gt(column string, value interface{}) Cond { return engine.Gt(column, value) }
+
+// Gte matches column >= value
+func Gte(column string, value interface{}) Cond { return engine.Gte(column, value) }
+
+// Lt matches column < value
+func Lt(column string, value interface{}) Cond { return engine.Lt(column, value) }
+
+// Lte matches column <= value
+func Lte(column string, value interface{}) Cond { return engine.Lte(column, value) }
+
+// Like matches column LIKE pattern
+func Like(column, pattern string) Cond { return engine.Like(column, pattern) }
+
+// ILike matches column ILIKE pattern (case-insensitive)
+func ILike(column, pattern string) Cond { return engine.ILike(column, pattern) }
+
+// In matches column IN (values...); accepts a slice or individual values
+func In(column string, values ...interface{}) Cond { return engine.In(column, values...) }
+
+// NotIn matches column NOT IN (values...)
+func NotIn(column string, values ...interface{}) Cond { return engine.NotIn(column, values...) }
+
+// Between matches low <= column <= high
+func Between(column string, low, high interface{}) Cond { return engine.Between(column, low, high) }
+
+// IsNull matches column IS NULL
+func IsNull(column string) Cond { return engine.IsNull(column) }
+
+// IsNotNull matches column IS NOT NULL
+func IsNotNull(column string) Cond { return engine.IsNotNull(column) }
+
+// And matches when every condition matches
+func And(conds ...Cond) Cond { return engine.And(conds...) }
+
+// Or matches when any condition matches
+func Or(conds ...Cond) Cond { return engine.Or(conds...) }
+
+// Not negates a condition
+func Not(cond Cond) Cond { return engine.Not(cond) }
+
+// Clause wraps hand-written SQL ($1, $2, ... relative to its own args, or :name with P) as a Cond
+// Usage: norm.Or(norm.Clause("age > $1", 18), norm.Eq("vip", true))
+func Clause(sql string, args ...interface{}) Cond { return engine.Clause(sql, args...) }
+
+// ============================================================
+// Expressions
+// ============================================================
+
+// Expression is SQL used in place of a value in Update, Set, BulkInsert rows,
+// DoUpdateSet and Where/Cond args; its ? placeholders are numbered with the query's
+type Expression = engine.Expression
+
+// Expr creates an expression with ? placeholders for args (?? is a literal ?)
+// Usage: norm.Table("accounts").Update("balance", norm.Expr("balance + ?", 10)).Where("id = $1", id).Exec(ctx)
+func Expr(sql string, args ...interface{}) Expression { return engine.Expr(sql, args...) }
+
+// Inc adds n to column; passed alone to Update it assigns column
+// Usage: norm.Table("posts").Update(norm.Inc("views", 1)).Where("id = $1", id).Exec(ctx)
+func Inc(column string, n interface{}) Expression { return engine.Inc(column, n) }
+
+// Col references another column
+// Usage: norm.Table("users").Update("display_name", norm.Col("username")).Where("display_name IS NULL").Exec(ctx)
+func Col(name string) Expression { return engine.Col(name) }
+
+// Now is the database's NOW()
+func Now() Expression { return engine.Now() }
+
+// ============================================================
+// Transactions
+// ============================================================
+
+// Tx is a transaction that queries can be built from (see Transaction and Begin)
+type Tx = engine.Tx
+
+var (
+	// ErrTxClosed is returned when a query runs on a committed or rolled back transaction
+	ErrTxClosed = engine.ErrTxClosed
+	// ErrTxPoolMismatch is returned when a transaction query targets a table on another pool
+	ErrTxPoolMismatch = engine.ErrTxPoolMismatch
+)
+
+// Transaction runs fn atomically; every query built from tx uses the same pgx.Tx.
+// The transaction commits when fn returns nil and rolls back on error or panic.
+// Usage:
+//
+//	err := norm.Transaction(ctx, func(tx *norm.Tx) error {
+//	    if _, err := tx.Table(order).Insert().Exec(ctx); err != nil {
+//	        return err
+//	    }
+//	    return tx.Transaction(ctx, func(sp *norm.Tx) error { // savepoint
+//	        _, err := sp.Table("users").Update("balance", 0).Where("id = $1", 1).Exec(ctx)
+//	        return err
+//	    })
+//	})
+func Transaction(ctx context.Context, fn func(tx *Tx) error, opts ...pgx.TxOptions) error {
+	return engine.Transaction(ctx, fn, opts...)
+}
+
+// Begin starts a transaction for manual Commit/Rollback
+// Pass a table name to bind the transaction to that table's pool up front,
+// otherwise it binds to the pool of the first query run on it.
+// Usage:
+//
+//	tx, err := norm.Begin(ctx, "orders")
+//	defer tx.Rollback(ctx)
+//	...
+//	err = tx.Commit(ctx)
+func Begin(ctx context.Context, table ...string) (*Tx, error) {
+	return engine.Begin(ctx, pgx.TxOptions{}, table...)
+}
+
+// BeginTx is like Begin but with explicit transaction options (isolation level, read-only, ...)
+func BeginTx(ctx context.Context, opts pgx.TxOptions, table ...string) (*Tx, error) {
+	return engine.Begin(ctx, opts, table...)
+}
+
 // Removed F() helper - use field pointers or string literals instead
 // Recommended approaches:
 // 1. Field pointers: From(user).Select(&user.Name, &user.Email)